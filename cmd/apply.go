@@ -0,0 +1,79 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/fhopfensperger/git-remote-cleanup/pkg"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// applyCmd represents the apply command
+var applyCmd = &cobra.Command{
+	Use:    "apply",
+	Short:  "Delete the branches of a plan file",
+	Long:   `Delete exactly the branches of a plan file written by plan, branches whose last commit changed since planning are skipped`,
+	PreRun: bindFlags,
+	Run: func(cmd *cobra.Command, args []string) {
+		planFile := viper.GetString("plan-file")
+		plan, err := pkg.ReadPlan(planFile)
+		if err != nil {
+			log.Err(err).Msgf("Could not read plan %s", planFile)
+			os.Exit(1)
+		}
+		dryRun = viper.GetBool("dry-run")
+		urls := make([]string, len(plan.Repos))
+		for i, repo := range plan.Repos {
+			urls[i] = repo.URL
+		}
+		results := processRepos(urls, func(i int, r string, auth transport.AuthMethod, logger zerolog.Logger) (*repoResult, error) {
+			var planned []string
+			for _, b := range plan.Repos[i].Branches {
+				planned = append(planned, b.Name)
+			}
+			result := newRepoResult(r)
+			versions, err := versionParserFor(repoConfig(r))
+			if err != nil {
+				return result, err
+			}
+			gitService := pkg.New(nil, auth, append(deletionOptions(), pkg.WithLogger(logger), pkg.WithVersionParser(versions))...)
+			deletedBranches, err := gitService.ApplyPlan(plan.Repos[i], dryRun)
+			result.add(&gitService, versions, planned, actionDelete, "planned")
+			if err != nil {
+				result.set(planned, actionFailed, "deletion failed")
+				return result, err
+			}
+			recordDeletion(result, planned, deletedBranches)
+			return result, nil
+		})
+		writeOutput(cmd, results)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(applyCmd)
+
+	flags := applyCmd.Flags()
+	flags.String("plan-file", "", "Plan file written by plan, e.g. plan.json")
+	_ = cobra.MarkFlagRequired(flags, "plan-file")
+	addDeletionFlags(flags)
+}
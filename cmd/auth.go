@@ -0,0 +1,253 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fhopfensperger/git-remote-cleanup/pkg"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/rs/zerolog"
+	"github.com/skeema/knownhosts"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/ssh"
+)
+
+// Modes of --host-key-checking
+const (
+	hostKeyStrict    = "strict"
+	hostKeyAcceptNew = "accept-new"
+)
+
+// knownHostsMu serializes the writes of new host keys, repos are processed concurrently
+var knownHostsMu sync.Mutex
+
+// addAuthFlags adds the flags which configure the authentication, used by all commands
+func addAuthFlags(pf *pflag.FlagSet) {
+	pf.String("ssh-key", "", "Private key for ssh repo urls, the ssh-agent is used if not set")
+	pf.String("ssh-key-passphrase", "", "Passphrase of the --ssh-key")
+	pf.String("known-hosts", "", "known_hosts file to verify the host keys of ssh repo urls (default ~/.ssh/known_hosts)")
+	pf.String("host-key-checking", hostKeyStrict, "Verification of unknown host keys: strict rejects them, accept-new adds them to the known_hosts file")
+	pf.String("credentials", "", "File with the credentials per host, used instead of --pat and --ssh-key for matching hosts")
+	pf.Bool("netrc", false, "Read the credentials of http repo urls without --pat from ~/.netrc")
+	pf.String("netrc-file", "", "Read the credentials of http repo urls without --pat from the netrc file")
+	pf.Bool("git-credential", false, "Ask git credential fill for the credentials of http repo urls without --pat, and approve or reject them after use")
+	for _, name := range []string{"ssh-key", "ssh-key-passphrase", "known-hosts", "host-key-checking", "credentials", "netrc", "netrc-file", "git-credential"} {
+		_ = viper.BindPFlag(name, pf.Lookup(name))
+	}
+}
+
+// authFor returns the authentication for the repo url, selected by its scheme: ssh urls like
+// git@github.com:fhopfensperger/my-repo.git use --ssh-key or the ssh-agent, http urls use --pat. The token and ssh key
+// of the entry of the repo in the config file, or of the --credentials matching the host, are used instead if set.
+func authFor(repoURL string) (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(repoURL)
+	if err != nil {
+		return nil, err
+	}
+	cred, found := repoCredential(repoURL)
+	if !found {
+		cred, found = credentialFor(endpoint.Host)
+	}
+	switch endpoint.Protocol {
+	case "ssh":
+		keyFile := viper.GetString("ssh-key")
+		if cred.SSHKey != "" {
+			keyFile = expandHome(cred.SSHKey)
+		}
+		return sshAuth(endpoint.User, keyFile)
+	case "http", "https":
+		if found {
+			return credentialAuth(cred, endpoint)
+		}
+		return httpAuth(endpoint)
+	}
+	return nil, nil
+}
+
+// credentialAuth returns the authentication with the token of the credential. A credential without a token, e.g. with
+// only an ssh-key for ssh urls of the same host, uses --pat, --netrc or --git-credential like repos without a credential.
+func credentialAuth(cred hostCredential, endpoint *transport.Endpoint) (transport.AuthMethod, error) {
+	if cred.Token == "" && cred.TokenFile == "" && cred.TokenEnv == "" {
+		return httpAuth(endpoint)
+	}
+	token, err := cred.token()
+	if err != nil {
+		return nil, err
+	}
+	if token == "" {
+		return nil, fmt.Errorf("the token of host %s is empty", cred.Host)
+	}
+	username := "123" // Using a PAT this can be anything except an empty string
+	if cred.Username != "" {
+		username = cred.Username
+	}
+	return &http.BasicAuth{Username: username, Password: token}, nil
+}
+
+// httpAuth returns the authentication with --pat, the netrc file with --netrc or git credential fill with
+// --git-credential, in this order
+func httpAuth(endpoint *transport.Endpoint) (transport.AuthMethod, error) {
+	if pat != "" {
+		return &http.BasicAuth{
+			Username: "123", // Using a PAT this can be anything except an empty string
+			Password: pat,
+		}, nil
+	}
+	if viper.GetBool("netrc") || viper.GetString("netrc-file") != "" {
+		file := expandHome(viper.GetString("netrc-file"))
+		if file == "" {
+			file = expandHome("~/.netrc")
+		}
+		login, found, err := readNetrc(file, endpoint.Host)
+		if err != nil {
+			return nil, fmt.Errorf("could not read netrc file %s: %w", file, err)
+		}
+		if found {
+			return &http.BasicAuth{Username: login.login, Password: login.password}, nil
+		}
+	}
+	if viper.GetBool("git-credential") {
+		return gitCredentialFill(endpoint)
+	}
+	return nil, nil
+}
+
+// completeAuth approves the credential of git credential fill after it was used successfully, and rejects it if the
+// authentication failed
+func completeAuth(auth transport.AuthMethod, err error, logger zerolog.Logger) {
+	credAuth, ok := auth.(*gitCredentialAuth)
+	if !ok {
+		return
+	}
+	switch {
+	case err == nil:
+		err = credAuth.approve()
+	case errors.Is(err, pkg.ErrAuthentication):
+		err = credAuth.reject()
+	default:
+		return
+	}
+	if err != nil {
+		logger.Warn().Err(err).Msg("Could not update the git credential")
+	}
+}
+
+// sshAuth returns the authentication with the key file, or with the ssh-agent if no key file is set
+func sshAuth(user string, keyFile string) (transport.AuthMethod, error) {
+	hostKeyCallback, err := hostKeyCallback()
+	if err != nil {
+		return nil, err
+	}
+	if keyFile != "" {
+		auth, err := gitssh.NewPublicKeysFromFile(user, keyFile, viper.GetString("ssh-key-passphrase"))
+		if err != nil {
+			return nil, fmt.Errorf("could not read ssh key %s: %w", keyFile, err)
+		}
+		auth.HostKeyCallback = hostKeyCallback
+		return auth, nil
+	}
+	auth, err := gitssh.NewSSHAgentAuth(user)
+	if err != nil {
+		return nil, fmt.Errorf("no --ssh-key set and the ssh-agent is not available: %w", err)
+	}
+	auth.HostKeyCallback = hostKeyCallback
+	return auth, nil
+}
+
+// hostKeyCallback verifies the host keys with the known_hosts file. With --host-key-checking accept-new the keys of
+// unknown hosts are added to the file, changed keys are always rejected.
+func hostKeyCallback() (ssh.HostKeyCallback, error) {
+	file := viper.GetString("known-hosts")
+	if file == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		file = filepath.Join(home, ".ssh", "known_hosts")
+	}
+
+	mode := viper.GetString("host-key-checking")
+	switch mode {
+	case hostKeyStrict:
+	case hostKeyAcceptNew:
+		if err := createFile(file); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("invalid --host-key-checking %s, use %s or %s", mode, hostKeyStrict, hostKeyAcceptNew)
+	}
+
+	callback, err := knownhosts.New(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not read known_hosts file %s: %w", file, err)
+	}
+	if mode == hostKeyStrict {
+		return callback.HostKeyCallback(), nil
+	}
+	return func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := callback(hostname, remote, key)
+		if !knownhosts.IsHostUnknown(err) {
+			return err
+		}
+		// Only real keys are added, the callback is also called with fake keys to find the known key algorithms
+		if _, parseErr := ssh.ParsePublicKey(key.Marshal()); parseErr != nil {
+			return err
+		}
+		return addKnownHost(file, hostname, remote, key)
+	}, nil
+}
+
+// addKnownHost appends the key of the host to the known_hosts file
+func addKnownHost(file string, hostname string, remote net.Addr, key ssh.PublicKey) error {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+
+	// Another repo of the same host might have added the key in the meantime
+	if callback, err := knownhosts.New(file); err == nil && callback(hostname, remote, key) == nil {
+		return nil
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return knownhosts.WriteKnownHost(f, hostname, remote, key)
+}
+
+// createFile creates the file and its directory, if the file does not exist
+func createFile(file string) error {
+	if _, err := os.Stat(file); !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
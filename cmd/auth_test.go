@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	"github.com/skeema/knownhosts"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func newHostKey(t *testing.T) ssh.PublicKey {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	key, err := ssh.NewPublicKey(pub)
+	assert.NoError(t, err)
+	return key
+}
+
+func setAuthConfig(t *testing.T, values map[string]string) {
+	for k, v := range values {
+		viper.Set(k, v)
+	}
+	t.Cleanup(func() {
+		for k := range values {
+			viper.Set(k, "")
+		}
+		viper.Set("host-key-checking", hostKeyStrict)
+	})
+}
+
+func Test_authFor_http(t *testing.T) {
+	pat = "secret"
+	defer func() { pat = "" }()
+
+	auth, err := authFor("https://github.com/fhopfensperger/my-repo.git")
+	assert.NoError(t, err)
+	assert.Equal(t, &http.BasicAuth{Username: "123", Password: "secret"}, auth)
+
+	auth, err = authFor("/tmp/my-repo.git")
+	assert.NoError(t, err)
+	assert.Nil(t, auth)
+}
+
+func Test_authFor_ssh_key(t *testing.T) {
+	dir := t.TempDir()
+	_, private, _ := ed25519.GenerateKey(rand.Reader)
+	block, err := ssh.MarshalPrivateKey(private, "")
+	assert.NoError(t, err)
+	keyFile := filepath.Join(dir, "id_ed25519")
+	assert.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(block), 0o600))
+	setAuthConfig(t, map[string]string{
+		"ssh-key":           keyFile,
+		"known-hosts":       filepath.Join(dir, "known_hosts"),
+		"host-key-checking": hostKeyAcceptNew,
+	})
+
+	auth, err := authFor("git@github.com:fhopfensperger/my-repo.git")
+
+	assert.NoError(t, err)
+	publicKeys, ok := auth.(*gitssh.PublicKeys)
+	assert.True(t, ok)
+	assert.Equal(t, "git", publicKeys.User)
+	assert.NotNil(t, publicKeys.HostKeyCallback)
+}
+
+func Test_authFor_ssh_key_not_found(t *testing.T) {
+	dir := t.TempDir()
+	setAuthConfig(t, map[string]string{
+		"ssh-key":           filepath.Join(dir, "unknown"),
+		"known-hosts":       filepath.Join(dir, "known_hosts"),
+		"host-key-checking": hostKeyAcceptNew,
+	})
+
+	_, err := authFor("ssh://git@github.com/fhopfensperger/my-repo.git")
+	assert.Error(t, err)
+}
+
+func Test_hostKeyCallback_accept_new(t *testing.T) {
+	knownHosts := filepath.Join(t.TempDir(), ".ssh", "known_hosts")
+	setAuthConfig(t, map[string]string{"known-hosts": knownHosts, "host-key-checking": hostKeyAcceptNew})
+	remote := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 22}
+	key := newHostKey(t)
+
+	callback, err := hostKeyCallback()
+	assert.NoError(t, err)
+	assert.NoError(t, callback("github.com:22", remote, key))
+
+	// The new key is verified strictly from now on
+	setAuthConfig(t, map[string]string{"known-hosts": knownHosts, "host-key-checking": hostKeyStrict})
+	callback, err = hostKeyCallback()
+	assert.NoError(t, err)
+	assert.NoError(t, callback("github.com:22", remote, key))
+	assert.True(t, knownhosts.IsHostKeyChanged(callback("github.com:22", remote, newHostKey(t))))
+}
+
+func Test_hostKeyCallback_strict(t *testing.T) {
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	setAuthConfig(t, map[string]string{"known-hosts": knownHosts, "host-key-checking": hostKeyStrict})
+
+	_, err := hostKeyCallback()
+	assert.Error(t, err, "missing known_hosts file")
+
+	assert.NoError(t, os.WriteFile(knownHosts, nil, 0o600))
+	callback, err := hostKeyCallback()
+	assert.NoError(t, err)
+	err = callback("github.com:22", &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 22}, newHostKey(t))
+	assert.True(t, knownhosts.IsHostUnknown(err))
+}
+
+func Test_hostKeyCallback_invalid_mode(t *testing.T) {
+	setAuthConfig(t, map[string]string{"known-hosts": filepath.Join(t.TempDir(), "known_hosts"), "host-key-checking": "no"})
+
+	_, err := hostKeyCallback()
+	assert.Error(t, err)
+}
@@ -17,8 +17,13 @@ limitations under the License.
 package cmd
 
 import (
+	"fmt"
+	"os"
+	"regexp"
+
 	"github.com/fhopfensperger/git-remote-cleanup/pkg"
-	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -27,20 +32,12 @@ var latest bool
 
 // branchCmd represents the branch command
 var branchCmd = &cobra.Command{
-	Use:   "branches",
-	Short: "Get remote branches",
-	Long:  `Get remote branches`,
+	Use:    "branches",
+	Short:  "Get remote branches",
+	Long:   `Get remote branches`,
+	PreRun: bindFlags,
 	Run: func(cmd *cobra.Command, args []string) {
-		checkRepos()
-		auth := http.BasicAuth{
-			Username: "123", // Using a PAT this can be anything except an empty string
-			Password: pat,
-		}
-		for _, r := range repos {
-			latest = viper.GetBool("latest")
-			gitService := pkg.New(nil, &auth)
-			gitService.GetRemoteBranches(r, filter, latest)
-		}
+		listRefs(cmd, false)
 	},
 }
 
@@ -48,6 +45,87 @@ func init() {
 	rootCmd.AddCommand(branchCmd)
 
 	flags := branchCmd.Flags()
-	flags.BoolP("latest", "l", false, "Print latest remote branch for filter")
+	flags.BoolP("latest", "l", false, "Print latest remote branch for filter, per component with --group-by")
+	flags.String("only", "", "Only get branches whose version satisfies the semver range, e.g. '<2.0.0'")
 	_ = viper.BindPFlag("latest", flags.Lookup("latest"))
 }
+
+// branchQuery are the settings of a repo for the branches and tags commands
+type branchQuery struct {
+	only     *pkg.Constraint
+	versions pkg.VersionParser
+	groupBy  *regexp.Regexp
+}
+
+// readBranchQuery reads the --only range, the version parser and the --group-by regex of the repo settings
+func readBranchQuery(v *viper.Viper) (branchQuery, error) {
+	only, err := constraintFor(v, "only")
+	if err != nil {
+		return branchQuery{}, err
+	}
+	versions, err := versionParserFor(v)
+	if err != nil {
+		return branchQuery{}, err
+	}
+	groupBy, err := groupByFor(v)
+	if err != nil {
+		return branchQuery{}, err
+	}
+	return branchQuery{only: only, versions: versions, groupBy: groupBy}, nil
+}
+
+// listRefs lists the branches, or the tags, of the repos, used by the branches and the tags command
+func listRefs(cmd *cobra.Command, tags bool) {
+	checkRepos()
+	checkFilter()
+	for _, r := range repos {
+		if _, err := readBranchQuery(repoConfig(r)); err != nil {
+			fmt.Printf("Invalid settings of repo %s: %v\n", r, err)
+			os.Exit(1)
+		}
+	}
+	latest = viper.GetBool("latest")
+	results := processRepos(repos, func(_ int, r string, auth transport.AuthMethod, logger zerolog.Logger) (*repoResult, error) {
+		result := newRepoResult(r)
+		v := repoConfig(r)
+		filters, err := filtersFor(v)
+		if err != nil {
+			return result, err
+		}
+		query, err := readBranchQuery(v)
+		if err != nil {
+			return result, err
+		}
+		opts := []pkg.Option{pkg.WithLogger(logger), pkg.WithVersionParser(query.versions), pkg.WithGroupBy(query.groupBy)}
+		if tags {
+			opts = append(opts, pkg.WithTags(archiveTagPrefix()))
+		}
+		gitService := pkg.New(nil, auth, opts...)
+		// The latest branches must satisfy --only, so they are picked after the constraint was applied
+		branches, err := gitService.GetRemoteBranchesMatching(r, filters, latest && query.only == nil)
+		if err != nil {
+			return result, err
+		}
+		var outside []string
+		if query.only != nil {
+			branches, outside = pkg.SplitByConstraint(branches, *query.only, query.versions)
+			if latest {
+				branches = pkg.LatestPerComponent(branches, query.groupBy)
+			}
+		}
+		action := actionFound
+		if latest {
+			action = actionLatest
+		}
+		result.add(&gitService, query.versions, branches, action, "")
+		if unversioned := pkg.Unversioned(branches, query.versions); len(unversioned) > 0 {
+			logger.Info().Msgf("Branches without a version: %v for repo %s", unversioned, r)
+			result.set(unversioned, action, "no version")
+		}
+		if query.only != nil {
+			result.addOutsideOnly(&gitService, query.versions, outside, *query.only)
+		}
+		return result, nil
+	})
+	writeOutput(cmd, results)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cast"
+	"github.com/spf13/viper"
+)
+
+// repoSettings are the settings which can be overridden per repo in the repos list of the config file
+var repoSettings = []string{
+	"refs", "mode", "filter", "filter-regex", "filter-glob", "exclude", "keep", "only", "version-scheme", "group-by",
+	"keep-patches", "keep-minors", "keep-majors", "keep-months", "keep-newest", "delete-prereleases", "delete-unversioned",
+	"only-merged", "older-than", "keep-newer-than", "username", "token", "token-file", "token-env", "ssh-key",
+}
+
+// filterSettings replace each other with their empty value, e.g. a filter-glob of a repo replaces the global filter
+var filterSettings = map[string]interface{}{"filter": "", "filter-regex": []string{}, "filter-glob": []string{}}
+
+// repoOverrides are the settings of the repos entries of the config file by repo url
+var repoOverrides = map[string]map[string]interface{}{}
+
+// parseRepos parses the repos from the flag, the environment variable or the config file. In the config file every
+// entry is either a repo url or a map with the url and the settings overridden for this repo, e.g.
+//
+//	repos:
+//	  - git@github.com:fhopfensperger/my-repo.git
+//	  - url: git@github.com:fhopfensperger/other-repo.git
+//	    filter: releases
+//	    keep-patches: 2
+func parseRepos(value interface{}) ([]string, map[string]map[string]interface{}, error) {
+	overrides := map[string]map[string]interface{}{}
+	entries, ok := value.([]interface{})
+	if !ok {
+		return cast.ToStringSlice(value), overrides, nil
+	}
+
+	var urls []string
+	for _, entry := range entries {
+		if url, ok := entry.(string); ok {
+			urls = append(urls, url)
+			continue
+		}
+		settings, err := cast.ToStringMapE(entry)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid repo %v: %w", entry, err)
+		}
+		url := cast.ToString(settings["url"])
+		if url == "" {
+			return nil, nil, fmt.Errorf("repo %v without url", entry)
+		}
+		delete(settings, "url")
+		for key := range settings {
+			if !containsString(repoSettings, key) {
+				return nil, nil, fmt.Errorf("invalid setting %s of repo %s, use one of %v", key, url, repoSettings)
+			}
+		}
+		urls = append(urls, url)
+		overrides[url] = settings
+	}
+	return urls, overrides, nil
+}
+
+// repoConfig returns the settings of the repo, the settings of its entry in the config file override the global ones.
+// The filters of the entry replace all global filters.
+func repoConfig(repo string) *viper.Viper {
+	v := viper.New()
+	for _, key := range viper.AllKeys() {
+		v.SetDefault(key, viper.Get(key))
+	}
+	overrides := repoOverrides[repo]
+	for key := range filterSettings {
+		if _, ok := overrides[key]; ok {
+			for other, empty := range filterSettings {
+				v.Set(other, empty)
+			}
+			break
+		}
+	}
+	for key, value := range overrides {
+		v.Set(key, value)
+	}
+	return v
+}
+
+// repoCredential returns the credential of the entry of the repo in the config file
+func repoCredential(repo string) (hostCredential, bool) {
+	settings := repoOverrides[repo]
+	cred := hostCredential{
+		Host:      repo,
+		Username:  cast.ToString(settings["username"]),
+		Token:     cast.ToString(settings["token"]),
+		TokenFile: cast.ToString(settings["token-file"]),
+		TokenEnv:  cast.ToString(settings["token-env"]),
+		SSHKey:    cast.ToString(settings["ssh-key"]),
+	}
+	return cred, cred != hostCredential{Host: repo}
+}
+
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
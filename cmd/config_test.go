@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fhopfensperger/git-remote-cleanup/pkg"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseRepos(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	_ = os.WriteFile(file, []byte(`repos:
+  - git@github.com:fhopfensperger/my-repo.git
+  - url: https://gitlab.example.com/group/other-repo.git
+    filter: releases/
+    exclude: [v1.0.0]
+    keep-patches: 2
+    token-env: GITLAB_TOKEN
+`), 0o644)
+	v := viper.New()
+	v.SetConfigFile(file)
+	assert.NoError(t, v.ReadInConfig())
+
+	urls, overrides, err := parseRepos(v.Get("repos"))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"git@github.com:fhopfensperger/my-repo.git", "https://gitlab.example.com/group/other-repo.git"}, urls)
+	assert.Equal(t, map[string]map[string]interface{}{
+		"https://gitlab.example.com/group/other-repo.git": {
+			"filter":       "releases/",
+			"exclude":      []interface{}{"v1.0.0"},
+			"keep-patches": 2,
+			"token-env":    "GITLAB_TOKEN",
+		},
+	}, overrides)
+}
+
+func Test_parseRepos_flag(t *testing.T) {
+	urls, overrides, err := parseRepos([]string{"git@github.com:fhopfensperger/my-repo.git"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"git@github.com:fhopfensperger/my-repo.git"}, urls)
+	assert.Empty(t, overrides)
+}
+
+func Test_parseRepos_invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"no-url", []interface{}{map[string]interface{}{"filter": "release"}}},
+		{"unknown-setting", []interface{}{map[string]interface{}{"url": "git@github.com:fhopfensperger/my-repo.git", "filtr": "release"}}},
+		{"invalid-entry", []interface{}{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := parseRepos(tt.value)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func Test_readSelection_overrides(t *testing.T) {
+	repo := "https://gitlab.example.com/group/other-repo.git"
+	repoOverrides = map[string]map[string]interface{}{repo: {"filter": "releases/", "keep-patches": 2, "older-than": "30d"}}
+	defer func() { repoOverrides = map[string]map[string]interface{}{} }()
+
+	sel, err := readSelection(repo)
+	assert.NoError(t, err)
+	assert.Equal(t, []pkg.Filter{pkg.SubstringFilter("releases/")}, sel.filters)
+	assert.Equal(t, 2, sel.retention.Patches)
+	assert.Equal(t, "30d", sel.olderThan)
+
+	// Repos without an entry use the global settings
+	sel, err = readSelection("git@github.com:fhopfensperger/my-repo.git")
+	assert.NoError(t, err)
+	globalFilters, _ := filtersFor(viper.GetViper())
+	assert.Equal(t, globalFilters, sel.filters)
+	assert.Equal(t, viper.GetInt("keep-patches"), sel.retention.Patches)
+
+	repoOverrides[repo]["older-than"] = "old"
+	_, err = readSelection(repo)
+	assert.Error(t, err)
+
+	repoOverrides[repo]["older-than"] = "30d"
+	repoOverrides[repo]["exclude"] = []interface{}{"semver:>=2.0.0 <"}
+	_, err = readSelection(repo)
+	assert.Error(t, err)
+}
+
+func Test_readSelection_constraints(t *testing.T) {
+	repo := "https://gitlab.example.com/group/other-repo.git"
+	repoOverrides = map[string]map[string]interface{}{repo: {"keep": ">=3.0.0", "only": "<4.0.0"}}
+	defer func() { repoOverrides = map[string]map[string]interface{}{} }()
+
+	sel, err := readSelection(repo)
+	assert.NoError(t, err)
+	assert.Equal(t, ">=3.0.0", sel.keep.String())
+	assert.Equal(t, "<4.0.0", sel.only.String())
+
+	sel, err = readSelection("git@github.com:fhopfensperger/my-repo.git")
+	assert.NoError(t, err)
+	assert.Nil(t, sel.keep)
+	assert.Nil(t, sel.only)
+
+	repoOverrides[repo]["keep"] = ">=three"
+	_, err = readSelection(repo)
+	assert.Error(t, err)
+}
+
+func Test_readSelection_version_scheme(t *testing.T) {
+	repo := "https://gitlab.example.com/group/other-repo.git"
+	repoOverrides = map[string]map[string]interface{}{repo: {"version-scheme": "calver", "keep-months": 6}}
+	defer func() { repoOverrides = map[string]map[string]interface{}{} }()
+
+	sel, err := readSelection(repo)
+	assert.NoError(t, err)
+	assert.Equal(t, pkg.CalVerParser{}, sel.retention.Versions)
+	assert.Equal(t, 6, sel.retention.Months)
+
+	sel, err = readSelection("git@github.com:fhopfensperger/my-repo.git")
+	assert.NoError(t, err)
+	assert.Equal(t, pkg.SemVerParser{}, sel.retention.Versions)
+
+	repoOverrides[repo]["version-scheme"] = "romver"
+	_, err = readSelection(repo)
+	assert.Error(t, err)
+}
+
+func Test_readSelection_mode(t *testing.T) {
+	repo := "https://gitlab.example.com/group/other-repo.git"
+	repoOverrides = map[string]map[string]interface{}{repo: {"mode": "stale", "older-than": "90d"}}
+	defer func() { repoOverrides = map[string]map[string]interface{}{} }()
+
+	sel, err := readSelection(repo)
+	assert.NoError(t, err)
+	assert.True(t, sel.stale)
+
+	repoOverrides[repo]["older-than"] = ""
+	_, err = readSelection(repo)
+	assert.Error(t, err)
+
+	repoOverrides[repo]["mode"] = "ancient"
+	_, err = readSelection(repo)
+	assert.Error(t, err)
+}
+
+func Test_repoCredential(t *testing.T) {
+	repo := "https://gitlab.example.com/group/other-repo.git"
+	repoOverrides = map[string]map[string]interface{}{repo: {"username": "oauth2", "token": "glpat-123", "filter": "release"}}
+	defer func() { repoOverrides = map[string]map[string]interface{}{} }()
+
+	cred, found := repoCredential(repo)
+	assert.True(t, found)
+	assert.Equal(t, hostCredential{Host: repo, Username: "oauth2", Token: "glpat-123"}, cred)
+
+	_, found = repoCredential("git@github.com:fhopfensperger/my-repo.git")
+	assert.False(t, found)
+}
+
+func Test_repoConfig_filters_replace_global_filters(t *testing.T) {
+	repo := "https://gitlab.example.com/group/other-repo.git"
+	repoOverrides = map[string]map[string]interface{}{repo: {"filter-glob": []interface{}{"releases/v*"}}}
+	defer func() { repoOverrides = map[string]map[string]interface{}{} }()
+
+	filters, err := filtersFor(repoConfig(repo))
+
+	assert.NoError(t, err)
+	glob, _ := pkg.GlobFilter("releases/v*")
+	assert.Equal(t, []pkg.Filter{glob}, filters)
+}
+
+func Test_filtersFor(t *testing.T) {
+	v := viper.New()
+	v.Set("filter", "release")
+	v.Set("filter-regex", []string{"^hotfix/v"})
+	v.Set("filter-glob", []string{"support/*"})
+
+	filters, err := filtersFor(v)
+	assert.NoError(t, err)
+	var names []string
+	for _, f := range filters {
+		names = append(names, f.String())
+	}
+	assert.Equal(t, []string{"release", "regex:^hotfix/v", "glob:support/*"}, names)
+
+	v.Set("filter-regex", []string{"(v"})
+	_, err = filtersFor(v)
+	assert.Error(t, err)
+}
+
+func Test_groupByFor(t *testing.T) {
+	v := viper.New()
+	groupBy, err := groupByFor(v)
+	assert.NoError(t, err)
+	assert.Nil(t, groupBy)
+
+	v.Set("group-by", "^release/([^/]+)/")
+	groupBy, err = groupByFor(v)
+	assert.NoError(t, err)
+	assert.Equal(t, "api", pkg.Component("refs/heads/release/api/v1.0.0", groupBy))
+
+	v.Set("group-by", "^release/")
+	_, err = groupByFor(v)
+	assert.Error(t, err)
+}
@@ -0,0 +1,121 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+// confirmer asks the user which of the branches selected for deletion are deleted. Repos processed in parallel are
+// confirmed one after another.
+type confirmer struct {
+	mu  sync.Mutex
+	in  *bufio.Reader
+	out io.Writer
+}
+
+func newConfirmer(in io.Reader, out io.Writer) *confirmer {
+	return &confirmer{in: bufio.NewReader(in), out: out}
+}
+
+// interactive reports if the user can confirm the deletion, stdin and stdout must be terminals
+func interactive() bool {
+	return isTerminal(os.Stdin) && isTerminal(os.Stdout)
+}
+
+func isTerminal(f *os.File) bool {
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// confirm shows the branches of the repo with their version, age and SHA from the result and returns the branches the
+// user confirmed. The user can confirm all, deselect branches by their number or skip the repo, the result records
+// the deselected and skipped branches. Without any more input the repo is skipped.
+func (c *confirmer) confirm(result *repoResult, branches []string, commitDates map[string]time.Time, now time.Time) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(branches) > 0 {
+		c.show(result, branches, commitDates, now)
+		fmt.Fprintf(c.out, "Delete %d branches? [y]es, [n]o to skip the repo, or the numbers of the branches to keep, e.g. 1,3: ", len(branches))
+		line, err := c.in.ReadString('\n')
+		answer := strings.ToLower(strings.TrimSpace(line))
+		switch {
+		case answer == "y" || answer == "yes":
+			return branches
+		case answer == "n" || answer == "no" || answer == "" && err != nil:
+			fmt.Fprintln(c.out)
+			result.set(branches, actionKeep, "repo skipped at confirmation")
+			return nil
+		case answer == "":
+			continue
+		}
+		deselected, err := deselect(branches, answer)
+		if err != nil {
+			fmt.Fprintln(c.out, err)
+			continue
+		}
+		result.set(deselected, actionKeep, "deselected at confirmation")
+		branches = difference(branches, deselected)
+	}
+	return nil
+}
+
+// show writes the numbered branches of the repo
+func (c *confirmer) show(result *repoResult, branches []string, commitDates map[string]time.Time, now time.Time) {
+	fmt.Fprintf(c.out, "Branches to delete from repo %s:\n", result.Repo)
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	for i, b := range branches {
+		version, sha := "", ""
+		for _, r := range result.Branches {
+			if r.Name == b {
+				version, sha = r.Version, r.SHA
+			}
+		}
+		if len(sha) > 7 {
+			sha = sha[:7]
+		}
+		age := "?"
+		if date, ok := commitDates[b]; ok {
+			age = fmt.Sprintf("%dd", int(now.Sub(date).Hours()/24))
+		}
+		fmt.Fprintf(w, "  %d)\t%s\t%s\t%s\t%s\n", i+1, b, version, age, sha)
+	}
+	_ = w.Flush()
+}
+
+// deselect returns the branches with the numbers of the answer, e.g. 1,3 or 1 3
+func deselect(branches []string, answer string) ([]string, error) {
+	var deselected []string
+	for _, field := range strings.FieldsFunc(answer, func(r rune) bool { return r == ',' || r == ' ' }) {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 1 || n > len(branches) {
+			return nil, fmt.Errorf("invalid answer %q, enter y, n or numbers between 1 and %d", answer, len(branches))
+		}
+		deselected = append(deselected, branches[n-1])
+	}
+	return deselected, nil
+}
@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_confirmer_confirm(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	branches := []string{"refs/heads/release/v1.0.0", "refs/heads/release/v1.0.1"}
+	commitDates := map[string]time.Time{"refs/heads/release/v1.0.0": now.AddDate(0, 0, -120)}
+	tests := []struct {
+		name      string
+		input     string
+		want      []string
+		wantKept  string
+		wantShown string
+	}{
+		{"yes", "y\n", branches, "", "1)  refs/heads/release/v1.0.0  v1.0.0  120d  1111111"},
+		{"no", "n\n", nil, "repo skipped at confirmation", "2)  refs/heads/release/v1.0.1  v1.0.1  ?     2222222"},
+		{"deselect", "2\nyes\n", branches[:1], "deselected at confirmation", "Delete 1 branches?"},
+		{"deselect-all", "1, 2\n", nil, "deselected at confirmation", ""},
+		{"invalid", "3\ny\n", branches, "", "invalid answer \"3\""},
+		{"no-input", "", nil, "repo skipped at confirmation", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := testResults()[0]
+			var out bytes.Buffer
+			c := newConfirmer(strings.NewReader(tt.input), &out)
+
+			got := c.confirm(result, append([]string{}, branches...), commitDates, now)
+
+			assert.Equal(t, tt.want, got)
+			assert.Contains(t, out.String(), tt.wantShown)
+			if tt.wantKept != "" {
+				assert.Equal(t, actionKeep, result.Branches[1].Action)
+				assert.Equal(t, tt.wantKept, result.Branches[1].Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// hostCredential is the authentication of the repos whose host matches the pattern Host, e.g. *.github.com.
+// The token is read from Token, the file TokenFile or the environment variable TokenEnv.
+type hostCredential struct {
+	Host      string `mapstructure:"host"`
+	Username  string `mapstructure:"username"`
+	Token     string `mapstructure:"token"`
+	TokenFile string `mapstructure:"token-file"`
+	TokenEnv  string `mapstructure:"token-env"`
+	SSHKey    string `mapstructure:"ssh-key"`
+}
+
+// credentials read from the --credentials file, the first one matching the host of a repo is used
+var credentials []hostCredential
+
+// readCredentials reads the credentials of the hosts from a yaml, toml or json file, e.g.
+//
+//	hosts:
+//	  - host: gitlab.example.com
+//	    username: oauth2
+//	    token-env: GITLAB_TOKEN
+func readCredentials(file string) ([]hostCredential, error) {
+	v := viper.New()
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("could not read credentials %s: %w", file, err)
+	}
+	var creds []hostCredential
+	if err := v.UnmarshalKey("hosts", &creds); err != nil {
+		return nil, fmt.Errorf("invalid credentials %s: %w", file, err)
+	}
+	for _, c := range creds {
+		if _, err := path.Match(c.Host, ""); err != nil || c.Host == "" {
+			return nil, fmt.Errorf("invalid host pattern %q in credentials %s", c.Host, file)
+		}
+	}
+	return creds, nil
+}
+
+// credentialFor returns the first credential whose pattern matches the host
+func credentialFor(host string) (hostCredential, bool) {
+	for _, c := range credentials {
+		if ok, _ := path.Match(c.Host, host); ok {
+			return c, true
+		}
+	}
+	return hostCredential{}, false
+}
+
+// token returns the token of the credential from the config, a file or an environment variable
+func (c hostCredential) token() (string, error) {
+	switch {
+	case c.Token != "":
+		return c.Token, nil
+	case c.TokenFile != "":
+		content, err := os.ReadFile(expandHome(c.TokenFile))
+		if err != nil {
+			return "", fmt.Errorf("could not read the token of host %s: %w", c.Host, err)
+		}
+		return strings.TrimSpace(string(content)), nil
+	case c.TokenEnv != "":
+		token, ok := os.LookupEnv(c.TokenEnv)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s with the token of host %s is not set", c.TokenEnv, c.Host)
+		}
+		return token, nil
+	}
+	return "", nil
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(file string) string {
+	if file != "~" && !strings.HasPrefix(file, "~/") {
+		return file
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return file
+	}
+	return filepath.Join(home, strings.TrimPrefix(file, "~"))
+}
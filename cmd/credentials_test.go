@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_readCredentials(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "credentials.yaml")
+	_ = os.WriteFile(file, []byte(`hosts:
+  - host: github.com
+    token: ghp_123
+  - host: "*.example.com"
+    username: oauth2
+    token-env: GITLAB_TOKEN
+  - host: gitea.example.com
+    ssh-key: ~/.ssh/gitea
+`), 0o644)
+
+	creds, err := readCredentials(file)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []hostCredential{
+		{Host: "github.com", Token: "ghp_123"},
+		{Host: "*.example.com", Username: "oauth2", TokenEnv: "GITLAB_TOKEN"},
+		{Host: "gitea.example.com", SSHKey: "~/.ssh/gitea"},
+	}, creds)
+}
+
+func Test_readCredentials_invalid(t *testing.T) {
+	dir := t.TempDir()
+	_, err := readCredentials(filepath.Join(dir, "unknown.yaml"))
+	assert.Error(t, err)
+
+	file := filepath.Join(dir, "credentials.toml")
+	_ = os.WriteFile(file, []byte("[[hosts]]\nhost = \"[\"\n"), 0o644)
+	_, err = readCredentials(file)
+	assert.Error(t, err)
+}
+
+func Test_authFor_credentials(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	_ = os.WriteFile(tokenFile, []byte("from-file\n"), 0o600)
+	t.Setenv("GITLAB_TOKEN", "from-env")
+	credentials = []hostCredential{
+		{Host: "github.com", Token: "ghp_123"},
+		{Host: "gitlab.*", Username: "oauth2", TokenEnv: "GITLAB_TOKEN"},
+		{Host: "gitea.example.com", TokenFile: tokenFile},
+		{Host: "missing.example.com", TokenEnv: "UNKNOWN_TOKEN"},
+		{Host: "ssh.example.com", SSHKey: "~/.ssh/gitea"},
+		{Host: "empty.example.com", TokenEnv: "EMPTY_TOKEN"},
+	}
+	t.Setenv("EMPTY_TOKEN", "")
+	pat = "global"
+	defer func() {
+		credentials = nil
+		pat = ""
+	}()
+
+	tests := []struct {
+		repo    string
+		want    *http.BasicAuth
+		wantErr bool
+	}{
+		{"https://github.com/fhopfensperger/my-repo.git", &http.BasicAuth{Username: "123", Password: "ghp_123"}, false},
+		{"https://gitlab.internal/group/my-repo.git", &http.BasicAuth{Username: "oauth2", Password: "from-env"}, false},
+		{"https://gitea.example.com/org/my-repo.git", &http.BasicAuth{Username: "123", Password: "from-file"}, false},
+		{"https://bitbucket.org/team/my-repo.git", &http.BasicAuth{Username: "123", Password: "global"}, false},
+		{"https://missing.example.com/org/my-repo.git", nil, true},
+		{"https://ssh.example.com/org/my-repo.git", &http.BasicAuth{Username: "123", Password: "global"}, false},
+		{"https://empty.example.com/org/my-repo.git", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.repo, func(t *testing.T) {
+			auth, err := authFor(tt.repo)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, auth)
+		})
+	}
+}
+
+func Test_expandHome(t *testing.T) {
+	home, _ := os.UserHomeDir()
+	assert.Equal(t, filepath.Join(home, ".ssh", "id_rsa"), expandHome("~/.ssh/id_rsa"))
+	assert.Equal(t, "/etc/token", expandHome("/etc/token"))
+}
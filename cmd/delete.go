@@ -17,34 +17,82 @@ limitations under the License.
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
 	"github.com/fhopfensperger/git-remote-cleanup/pkg"
-	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/rs/zerolog"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
-var excludes []string
 var dryRun bool
 
+// selection selects the branches to delete of a repo
+type selection struct {
+	filters    []pkg.Filter
+	excludes   []string
+	retention  pkg.RetentionPolicy
+	onlyMerged bool
+	// tags selects tags instead of branches, set by --refs tags
+	tags bool
+	// stale selects merged branches older than olderThan instead of applying the retention policy, set by --mode stale
+	stale bool
+	// keep protects and only restricts the branches by their version, nil if not set
+	keep *pkg.Constraint
+	only *pkg.Constraint
+	// olderThan and keepNewerThan are the ages as set, for the reasons in the results
+	olderThan     string
+	keepNewerThan string
+}
+
 // deleteCmd represents the delete command
 var deleteCmd = &cobra.Command{
-	Use:   "delete",
-	Short: "Delete old branches, keeps every latest hotfix version",
-	Long:  `Delete old branches, keeps every latest hotfix version`,
+	Use:    "delete",
+	Short:  "Delete old branches, keeps every latest hotfix version",
+	Long:   `Delete old branches, keeps every latest hotfix version`,
+	PreRun: bindFlags,
 	Run: func(cmd *cobra.Command, args []string) {
 		checkRepos()
-		auth := http.BasicAuth{
-			Username: "123", // Using a PAT this can be anything except an empty string
-			Password: pat,
-		}
-		excludes = viper.GetStringSlice("exclude")
+		checkFilter()
+		checkSelections()
 		dryRun = viper.GetBool("dry-run")
-		for _, r := range repos {
-			gitService := pkg.New(nil, &auth)
-			branches := gitService.GetRemoteBranches(r, filter, false)
-			branches = pkg.FilterBranches(branches)
-			gitService.CleanBranches(branches, excludes, dryRun)
+		var confirm *confirmer
+		if !dryRun && !viper.GetBool("yes") && interactive() {
+			// The prompt is written to stderr like the logs, stdout is kept for the results of --output
+			confirm = newConfirmer(os.Stdin, cmd.ErrOrStderr())
 		}
+		results := processRepos(repos, func(_ int, r string, auth transport.AuthMethod, logger zerolog.Logger) (*repoResult, error) {
+			sel, _ := readSelection(r)
+			gitService := pkg.New(nil, auth, append(append(deletionOptions(), sel.options()...), pkg.WithLogger(logger))...)
+			branchesToDelete, result, err := selectBranches(&gitService, r, sel, logger)
+			if err != nil {
+				return result, err
+			}
+			branchesToDelete, err = excludeBranches(branchesToDelete, sel, result, logger)
+			if err != nil {
+				return result, err
+			}
+			if confirm != nil && len(branchesToDelete) > 0 {
+				commitDates, err := gitService.CommitDates(branchesToDelete)
+				if err != nil {
+					return result, err
+				}
+				branchesToDelete = confirm.confirm(result, branchesToDelete, commitDates, time.Now())
+			}
+			deletedBranches, err := gitService.CleanBranches(branchesToDelete, nil, dryRun)
+			if err != nil {
+				result.set(branchesToDelete, actionFailed, "deletion failed")
+				return result, err
+			}
+			recordDeletion(result, branchesToDelete, deletedBranches)
+			return result, nil
+		})
+		writeOutput(cmd, results)
 	},
 }
 
@@ -52,10 +100,326 @@ func init() {
 	rootCmd.AddCommand(deleteCmd)
 
 	flags := deleteCmd.Flags()
-	flags.StringSliceP("exclude", "e", []string{}, "Exclude branches, e.g. v1.0.1")
-	_ = viper.BindPFlag("exclude", flags.Lookup("exclude"))
+	addSelectionFlags(flags)
+	addDeletionFlags(flags)
+	flags.BoolP("yes", "y", false, "Delete without confirmation, the branches are only confirmed if stdin and stdout are terminals")
+}
+
+// addSelectionFlags adds the flags which select the branches to delete, used by delete and plan
+func addSelectionFlags(flags *pflag.FlagSet) {
+	flags.String("refs", "branches", "Which refs are cleaned up: branches or tags")
+	flags.String("mode", "release", "How branches are selected: release (by version, see retention policy) or stale (merged and older than --older-than, regardless of their version)")
+	flags.StringSliceP("exclude", "e", []string{}, "Exclude branches by name or version, or by glob:, regex: or semver: rules, e.g. v1.0.1 or 'semver:>=2.0.0 <2.1.0'")
+	flags.Int("keep-patches", 1, "Number of latest patch versions to keep per minor version, 0 keeps all")
+	flags.Int("keep-minors", 0, "Number of latest minor versions to keep per major version, 0 keeps all")
+	flags.Int("keep-majors", 0, "Number of latest major versions to keep, 0 keeps all")
+	flags.Int("keep-months", 0, "Number of latest months to keep with --version-scheme calver, 0 keeps all")
+	flags.Bool("keep-newest", true, "Always keep the newest branch")
+	flags.Bool("delete-unversioned", false, "Delete branches without a version, e.g. release/next, they are always kept otherwise")
+	flags.Bool("delete-prereleases", false, "Delete the pre-releases of released versions, e.g. v2.0.0-rc.1 if v2.0.0 exists")
+	flags.String("keep", "", "Never delete branches whose version satisfies the semver range, e.g. '>=3.0.0'")
+	flags.String("only", "", "Only consider branches whose version satisfies the semver range, e.g. '<2.0.0'")
+	flags.Bool("only-merged", false, "Only delete branches which are merged into the default branch")
+	flags.String("older-than", "", "Delete branches whose last commit is older, regardless of their version, e.g. 180d")
+	flags.String("keep-newer-than", "", "Keep branches whose last commit is newer, regardless of their version, e.g. 30d")
+}
 
+// addDeletionFlags adds the flags which control how branches are deleted, used by delete and apply
+func addDeletionFlags(flags *pflag.FlagSet) {
 	flags.Bool("dry-run", false, "Perform dry run, do not delete anything")
-	_ = viper.BindPFlag("dry-run", flags.Lookup("dry-run"))
+	flags.Bool("archive-as-tag", false, "Archive every deleted branch as a tag pointing at its last commit")
+	flags.String("archive-tag-prefix", pkg.DefaultArchiveTagPrefix, "Prefix of the archive tags, e.g. archive/ creates archive/release/v1.1.0")
+	flags.String("backup-dir", "", "Write a git bundle of the branches into the directory before deleting them, see restore")
+}
+
+// readSelection reads the flags added by addSelectionFlags for the repo, they can be overridden per repo in the
+// config file
+func readSelection(repo string) (selection, error) {
+	v := repoConfig(repo)
+	retention, err := retentionPolicyFromConfig(v)
+	if err != nil {
+		return selection{}, err
+	}
+	filters, err := filtersFor(v)
+	if err != nil {
+		return selection{}, err
+	}
+	excludes := v.GetStringSlice("exclude")
+	if _, err := pkg.ParseExclusions(excludes); err != nil {
+		return selection{}, fmt.Errorf("invalid --exclude: %w", err)
+	}
+	keep, err := constraintFor(v, "keep")
+	if err != nil {
+		return selection{}, err
+	}
+	only, err := constraintFor(v, "only")
+	if err != nil {
+		return selection{}, err
+	}
+	refs := v.GetString("refs")
+	if refs != "" && refs != "branches" && refs != "tags" {
+		return selection{}, fmt.Errorf("invalid --refs %q, must be branches or tags", refs)
+	}
+	mode := v.GetString("mode")
+	if mode != "" && mode != "release" && mode != "stale" {
+		return selection{}, fmt.Errorf("invalid --mode %q, must be release or stale", mode)
+	}
+	if mode == "stale" && retention.OlderThan == 0 {
+		return selection{}, fmt.Errorf("--mode stale needs --older-than")
+	}
+	return selection{
+		filters:       filters,
+		excludes:      excludes,
+		retention:     retention,
+		onlyMerged:    v.GetBool("only-merged"),
+		tags:          refs == "tags",
+		stale:         mode == "stale",
+		keep:          keep,
+		only:          only,
+		olderThan:     v.GetString("older-than"),
+		keepNewerThan: v.GetString("keep-newer-than"),
+	}, nil
+}
+
+// options returns the options for pkg.New which select and sort the refs like the selection
+func (s selection) options() []pkg.Option {
+	opts := []pkg.Option{pkg.WithVersionParser(s.retention.Versions)}
+	if s.tags {
+		opts = append(opts, pkg.WithTags(archiveTagPrefix()))
+	}
+	return opts
+}
+
+// checkSelections exits if the selection of a repo is invalid
+func checkSelections() {
+	for _, r := range repos {
+		if _, err := readSelection(r); err != nil {
+			fmt.Printf("Invalid settings of repo %s: %v\n", r, err)
+			os.Exit(1)
+		}
+	}
+}
+
+// archiveTagPrefix returns the --archive-tag-prefix, also for commands without the flag, so the archive tags are never
+// selected by --refs tags or the tags command
+func archiveTagPrefix() string {
+	if prefix := viper.GetString("archive-tag-prefix"); prefix != "" {
+		return prefix
+	}
+	return pkg.DefaultArchiveTagPrefix
+}
+
+// deletionOptions returns the options for pkg.New from the flags added by addDeletionFlags
+func deletionOptions() []pkg.Option {
+	var opts []pkg.Option
+	if viper.GetBool("archive-as-tag") {
+		opts = append(opts, pkg.WithArchiveTags(viper.GetString("archive-tag-prefix")))
+	}
+	if backupDir := viper.GetString("backup-dir"); backupDir != "" {
+		opts = append(opts, pkg.WithBackup(backupDir))
+	}
+	return opts
+}
+
+// selectBranches gets the remote branches of the repo and selects the ones to delete, the exclusion list is not applied.
+// The result contains all branches with the reason why they are kept or deleted.
+func selectBranches(gitService *pkg.RemoteBranch, repo string, sel selection, logger zerolog.Logger) ([]string, *repoResult, error) {
+	retention := sel.retention
+	result := newRepoResult(repo)
+	branches, err := gitService.GetRemoteBranchesMatching(repo, sel.filters, false)
+	if err != nil {
+		return nil, result, err
+	}
+	if sel.only != nil {
+		var outside []string
+		branches, outside = pkg.SplitByConstraint(branches, *sel.only, sel.retention.Versions)
+		result.addOutsideOnly(gitService, retention.Versions, outside, *sel.only)
+	}
+	if sel.stale {
+		return selectStaleBranches(gitService, repo, branches, sel, result, logger)
+	}
+	result.add(gitService, retention.Versions, branches, actionKeep, "kept by retention policy")
+
+	branchesToDelete := pkg.FilterBranches(branches, retention)
+	result.set(branchesToDelete, actionDelete, "not kept by retention policy")
+	if retention.DeletePrereleases {
+		for _, group := range pkg.GroupByComponent(branches, retention.GroupBy) {
+			result.set(pkg.ReleasedPrereleases(group, retention.Versions), actionDelete, "pre-release of a released version")
+		}
+	}
+	if unversioned := pkg.Unversioned(branches, retention.Versions); len(unversioned) > 0 {
+		if retention.DeleteUnversioned {
+			result.set(intersection(unversioned, branchesToDelete), actionDelete, "no version, deleted by --delete-unversioned")
+		} else {
+			logger.Info().Msgf("Keeping branches without a version %v of repo %s", unversioned, repo)
+			result.set(unversioned, actionKeep, "no version")
+		}
+	}
+
+	if retention.UsesCommitDates() {
+		commitDates, err := gitService.CommitDates(branches)
+		if err != nil {
+			return nil, result, err
+		}
+		filteredBranches := pkg.FilterBranchesByAge(branches, branchesToDelete, commitDates, retention, time.Now())
+		result.set(difference(filteredBranches, branchesToDelete), actionDelete, "last commit older than "+sel.olderThan)
+		newest := pkg.LatestPerComponent(branches, retention.GroupBy)
+		for _, b := range difference(branchesToDelete, filteredBranches) {
+			if retention.KeepNewest && containsString(newest, b) {
+				result.set([]string{b}, actionKeep, "newest branch")
+			} else {
+				result.set([]string{b}, actionKeep, "last commit newer than "+sel.keepNewerThan)
+			}
+		}
+		branchesToDelete = filteredBranches
+	}
+	branchesToDelete = keepProtected(branchesToDelete, sel, result)
+	if sel.onlyMerged {
+		var unmerged []string
+		branchesToDelete, unmerged, err = gitService.MergedBranches(branchesToDelete)
+		if errors.Is(err, pkg.ErrNoDefaultBranch) {
+			logger.Warn().Msgf("Could not resolve the default branch of repo %s, treating branches %v as unmerged", repo, unmerged)
+		} else if err != nil {
+			return nil, result, err
+		}
+		result.set(unmerged, actionKeep, "not merged into the default branch")
+	}
+	return branchesToDelete, result, nil
+}
+
+// keepProtected removes the branches whose version satisfies --keep from the branches to delete
+func keepProtected(branchesToDelete []string, sel selection, result *repoResult) []string {
+	if sel.keep == nil {
+		return branchesToDelete
+	}
+	protected, branchesToDelete := pkg.SplitByConstraint(branchesToDelete, *sel.keep, sel.retention.Versions)
+	result.set(protected, actionKeep, "kept by --keep "+sel.keep.String())
+	return branchesToDelete
+}
+
+// selectStaleBranches selects the branches for --mode stale: merged into the default branch and the last commit older
+// than --older-than. The retention policy is not used, only --only and --keep select branches by their version.
+func selectStaleBranches(gitService *pkg.RemoteBranch, repo string, branches []string, sel selection, result *repoResult, logger zerolog.Logger) ([]string, *repoResult, error) {
+	result.add(gitService, sel.retention.Versions, branches, actionKeep, "last commit newer than "+sel.olderThan)
+	if defaultBranch := gitService.DefaultBranch(); containsString(branches, defaultBranch) {
+		branches = difference(branches, []string{defaultBranch})
+		result.set([]string{defaultBranch}, actionKeep, "default branch")
+	}
+	commitDates, err := gitService.CommitDates(branches)
+	if err != nil {
+		return nil, result, err
+	}
+	branchesToDelete := pkg.StaleBranches(branches, commitDates, sel.retention.OlderThan, time.Now())
+	result.set(branchesToDelete, actionDelete, "stale, last commit older than "+sel.olderThan)
+	branchesToDelete = keepProtected(branchesToDelete, sel, result)
+
+	branchesToDelete, unmerged, err := gitService.MergedBranches(branchesToDelete)
+	if errors.Is(err, pkg.ErrNoDefaultBranch) {
+		logger.Warn().Msgf("Could not resolve the default branch of repo %s, treating branches %v as unmerged", repo, unmerged)
+	} else if err != nil {
+		return nil, result, err
+	}
+	result.set(unmerged, actionKeep, "not merged into the default branch")
+	return branchesToDelete, result, nil
+}
+
+// excludeBranches removes the branches which match the exclusion list, the result records the matching rule
+func excludeBranches(branchesToDelete []string, sel selection, result *repoResult, logger zerolog.Logger) ([]string, error) {
+	branchesToDelete, excluded, err := pkg.ExcludeBranches(branchesToDelete, sel.excludes, sel.retention.Versions)
+	if err != nil {
+		return nil, err
+	}
+	for b, exclude := range excluded {
+		logger.Info().Msgf("Excluding branch %s as it matches the exclusion list %s", b, exclude)
+		result.set([]string{b}, actionKeep, "excluded by "+exclude)
+	}
+	return branchesToDelete, nil
+}
+
+// recordDeletion sets the action of the branches which were passed to CleanBranches, on a dry run they keep the
+// delete action
+func recordDeletion(result *repoResult, branchesToDelete []string, deletedBranches []string) {
+	if dryRun {
+		return
+	}
+	result.setAction(deletedBranches, actionDeleted)
+	result.set(difference(branchesToDelete, deletedBranches), actionSkipped, "changed since listing")
+}
+
+// difference returns the elements of a which are not in b
+func difference(a []string, b []string) []string {
+	var diff []string
+	for _, x := range a {
+		found := false
+		for _, y := range b {
+			if x == y {
+				found = true
+				break
+			}
+		}
+		if !found {
+			diff = append(diff, x)
+		}
+	}
+	return diff
+}
+
+// intersection returns the elements of a which are also in b
+func intersection(a []string, b []string) []string {
+	return difference(a, difference(a, b))
+}
+
+// retentionPolicyFromConfig builds the retention policy from flags, environment variables and the config file
+func retentionPolicyFromConfig(v *viper.Viper) (pkg.RetentionPolicy, error) {
+	olderThan, err := parseAge(v, "older-than")
+	if err != nil {
+		return pkg.RetentionPolicy{}, err
+	}
+	keepNewerThan, err := parseAge(v, "keep-newer-than")
+	if err != nil {
+		return pkg.RetentionPolicy{}, err
+	}
+	versions, err := versionParserFor(v)
+	if err != nil {
+		return pkg.RetentionPolicy{}, err
+	}
+	groupBy, err := groupByFor(v)
+	if err != nil {
+		return pkg.RetentionPolicy{}, err
+	}
+	return pkg.RetentionPolicy{
+		Patches:           v.GetInt("keep-patches"),
+		Minors:            v.GetInt("keep-minors"),
+		Majors:            v.GetInt("keep-majors"),
+		Months:            v.GetInt("keep-months"),
+		KeepNewest:        v.GetBool("keep-newest"),
+		DeletePrereleases: v.GetBool("delete-prereleases"),
+		DeleteUnversioned: v.GetBool("delete-unversioned"),
+		OlderThan:         olderThan,
+		KeepNewerThan:     keepNewerThan,
+		Versions:          versions,
+		GroupBy:           groupBy,
+	}, nil
+}
+
+// parseAge reads the age of the given key
+func parseAge(v *viper.Viper, key string) (time.Duration, error) {
+	age, err := pkg.ParseAge(v.GetString(key))
+	if err != nil {
+		return 0, fmt.Errorf("invalid --%s: %w", key, err)
+	}
+	return age, nil
+}
 
+// constraintFor reads the semver range of the given key, nil if it is not set
+func constraintFor(v *viper.Viper, key string) (*pkg.Constraint, error) {
+	s := v.GetString(key)
+	if s == "" {
+		return nil, nil
+	}
+	c, err := pkg.ParseConstraint(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --%s: %w", key, err)
+	}
+	return &c, nil
 }
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fhopfensperger/git-remote-cleanup/pkg"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+)
+
+// staleRepo creates a bare repo whose branches all point to a merged commit of 2020
+func staleRepo(t *testing.T, branches ...string) string {
+	dir := t.TempDir()
+	work := filepath.Join(dir, "work")
+	bare := filepath.Join(dir, "repo.git")
+	run := func(args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Env = append(cmd.Environ(), "GIT_AUTHOR_DATE=2020-01-01T00:00:00Z", "GIT_COMMITTER_DATE=2020-01-01T00:00:00Z")
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	run("init", "-q", "-b", "master", work)
+	run("-C", work, "-c", "user.name=test", "-c", "user.email=test@test", "commit", "-q", "--allow-empty", "-m", "initial")
+	for _, b := range branches {
+		run("-C", work, "branch", b)
+	}
+	run("clone", "-q", "--bare", work, bare)
+	return bare
+}
+
+func Test_selectBranches_stale_only_and_keep(t *testing.T) {
+	repo := staleRepo(t, "feature/old", "release/v2.0.0", "release/v3.0.0")
+	filter, err := pkg.GlobFilter("*/*")
+	assert.NoError(t, err)
+	keep, err := pkg.ParseConstraint(">=3.0.0")
+	assert.NoError(t, err)
+	sel := selection{
+		filters:   []pkg.Filter{filter},
+		stale:     true,
+		olderThan: "1h",
+		keep:      &keep,
+	}
+	sel.retention.OlderThan = time.Hour
+	sel.retention.Versions = pkg.SemVerParser{}
+	gitService := pkg.New(nil, nil, sel.options()...)
+
+	branchesToDelete, result, err := selectBranches(&gitService, repo, sel, zerolog.Nop())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"refs/heads/feature/old", "refs/heads/release/v2.0.0"}, branchesToDelete)
+	assert.Contains(t, result.Branches, branchResult{Name: "refs/heads/release/v3.0.0", SHA: gitService.SHA("refs/heads/release/v3.0.0"), Version: "v3.0.0", Action: actionKeep, Reason: "kept by --keep >=3.0.0"})
+
+	only, err := pkg.ParseConstraint("<3.0.0")
+	assert.NoError(t, err)
+	sel.only = &only
+	gitService = pkg.New(nil, nil, sel.options()...)
+	branchesToDelete, result, err = selectBranches(&gitService, repo, sel, zerolog.Nop())
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"refs/heads/release/v2.0.0"}, branchesToDelete)
+	assert.Contains(t, result.Branches, branchResult{Name: "refs/heads/feature/old", SHA: gitService.SHA("refs/heads/feature/old"), Action: actionKeep, Reason: "no version"})
+	assert.Contains(t, result.Branches, branchResult{Name: "refs/heads/release/v3.0.0", SHA: gitService.SHA("refs/heads/release/v3.0.0"), Version: "v3.0.0", Action: actionKeep, Reason: "outside --only <3.0.0"})
+}
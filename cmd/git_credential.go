@@ -0,0 +1,90 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+)
+
+// gitCredentialAuth is the authentication returned by git credential fill, it is approved or rejected after its use
+type gitCredentialAuth struct {
+	*http.BasicAuth
+	// credential is the output of git credential fill, which is passed to git credential approve or reject
+	credential string
+}
+
+// gitCredentialFill asks the configured git credential helpers for the credentials of the endpoint. Terminal prompts
+// are disabled, so a missing credential is an error instead of blocking.
+func gitCredentialFill(endpoint *transport.Endpoint) (*gitCredentialAuth, error) {
+	host := endpoint.Host
+	if endpoint.Port != 0 {
+		host = fmt.Sprintf("%s:%d", host, endpoint.Port)
+	}
+	input := fmt.Sprintf("protocol=%s\nhost=%s\npath=%s\n", endpoint.Protocol, host, strings.TrimPrefix(endpoint.Path, "/"))
+	if endpoint.User != "" {
+		input += "username=" + endpoint.User + "\n"
+	}
+
+	out, err := gitCredential("fill", input+"\n")
+	if err != nil {
+		return nil, err
+	}
+	auth := &gitCredentialAuth{BasicAuth: &http.BasicAuth{}, credential: out}
+	for _, line := range strings.Split(out, "\n") {
+		key, value, _ := strings.Cut(line, "=")
+		switch key {
+		case "username":
+			auth.Username = value
+		case "password":
+			auth.Password = value
+		}
+	}
+	return auth, nil
+}
+
+// approve stores the credential in the git credential helpers, after it was used successfully
+func (a *gitCredentialAuth) approve() error {
+	_, err := gitCredential("approve", a.credential+"\n")
+	return err
+}
+
+// reject removes the credential from the git credential helpers, after the authentication failed
+func (a *gitCredentialAuth) reject() error {
+	_, err := gitCredential("reject", a.credential+"\n")
+	return err
+}
+
+// gitCredential runs git credential with the action and input
+func gitCredential(action string, input string) (string, error) {
+	cmd := exec.Command("git", "credential", action)
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	cmd.Stdin = strings.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git credential %s: %w: %s", action, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
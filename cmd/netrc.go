@@ -0,0 +1,98 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// netrcLogin is the login of a machine in a netrc file
+type netrcLogin struct {
+	login    string
+	password string
+}
+
+// readNetrc returns the login of the host from the netrc file, the default entry is used if no machine matches
+func readNetrc(file string, host string) (netrcLogin, bool, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return netrcLogin{}, false, err
+	}
+	defer f.Close()
+
+	var tokens []string
+	scanner := bufio.NewScanner(f)
+	inMacro := false
+	for scanner.Scan() {
+		line := scanner.Text()
+		// A macro definition ends with an empty line
+		if inMacro {
+			inMacro = strings.TrimSpace(line) != ""
+			continue
+		}
+		fields := strings.Fields(line)
+		for i, field := range fields {
+			if field == "macdef" {
+				inMacro = true
+				fields = fields[:i]
+				break
+			}
+		}
+		tokens = append(tokens, fields...)
+	}
+	if err := scanner.Err(); err != nil {
+		return netrcLogin{}, false, err
+	}
+
+	var found, fallback *netrcLogin
+	var current *netrcLogin
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "machine":
+			current = &netrcLogin{}
+			if i+1 < len(tokens) && tokens[i+1] == host && found == nil {
+				found = current
+			}
+			i++
+		case "default":
+			current = &netrcLogin{}
+			if fallback == nil {
+				fallback = current
+			}
+		case "login", "password":
+			if current != nil && i+1 < len(tokens) {
+				if tokens[i] == "login" {
+					current.login = tokens[i+1]
+				} else {
+					current.password = tokens[i+1]
+				}
+			}
+			i++
+		case "account":
+			i++
+		}
+	}
+	switch {
+	case found != nil:
+		return *found, true, nil
+	case fallback != nil:
+		return *fallback, true, nil
+	}
+	return netrcLogin{}, false, nil
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_readNetrc(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".netrc")
+	_ = os.WriteFile(file, []byte(`machine github.com
+  login octocat
+  password ghp_123
+macdef init
+  machine gitlab.example.com login macro password macro
+
+machine gitlab.example.com login oauth2 password glpat-123 account dev
+default login anonymous password guest
+`), 0o600)
+
+	tests := []struct {
+		host      string
+		want      netrcLogin
+		wantFound bool
+	}{
+		{"github.com", netrcLogin{login: "octocat", password: "ghp_123"}, true},
+		{"gitlab.example.com", netrcLogin{login: "oauth2", password: "glpat-123"}, true},
+		{"gitea.example.com", netrcLogin{login: "anonymous", password: "guest"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			login, found, err := readNetrc(file, tt.host)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantFound, found)
+			assert.Equal(t, tt.want, login)
+		})
+	}
+}
+
+func Test_readNetrc_no_default(t *testing.T) {
+	file := filepath.Join(t.TempDir(), ".netrc")
+	_ = os.WriteFile(file, []byte("machine github.com login octocat password ghp_123\n"), 0o600)
+
+	_, found, err := readNetrc(file, "gitlab.example.com")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	_, _, err = readNetrc(file+"unknown", "github.com")
+	assert.Error(t, err)
+}
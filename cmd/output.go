@@ -0,0 +1,193 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fhopfensperger/git-remote-cleanup/pkg"
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+)
+
+// Actions of a branch in the structured output
+const (
+	actionFound   = "found"
+	actionLatest  = "latest"
+	actionKeep    = "keep"
+	actionDelete  = "delete"
+	actionDeleted = "deleted"
+	actionSkipped = "skipped"
+	actionFailed  = "failed"
+)
+
+var outputFormats = []string{"json", "yaml", "csv", "table"}
+
+// repoResult is the structured output of a repo
+type repoResult struct {
+	Repo     string         `json:"repo" yaml:"repo"`
+	Error    string         `json:"error,omitempty" yaml:"error,omitempty"`
+	Branches []branchResult `json:"branches" yaml:"branches"`
+}
+
+// branchResult is the structured output of a branch
+type branchResult struct {
+	Name    string `json:"name" yaml:"name"`
+	SHA     string `json:"sha" yaml:"sha"`
+	Version string `json:"version" yaml:"version"`
+	Action  string `json:"action" yaml:"action"`
+	Reason  string `json:"reason,omitempty" yaml:"reason,omitempty"`
+}
+
+func newRepoResult(repo string) *repoResult {
+	return &repoResult{Repo: repo, Branches: []branchResult{}}
+}
+
+// fail records the error of the repo
+func (r *repoResult) fail(logger zerolog.Logger, err error) {
+	logger.Err(err).Msgf("Could not process repo %s", r.Repo)
+	r.Error = err.Error()
+}
+
+// add adds the branches with the action and reason, their versions are parsed by the parser of the --version-scheme
+func (r *repoResult) add(gitService *pkg.RemoteBranch, versions pkg.VersionParser, branches []string, action string, reason string) {
+	for _, b := range branches {
+		version, _ := versions.Parse(b)
+		r.Branches = append(r.Branches, branchResult{Name: b, SHA: gitService.SHA(b), Version: version.Original, Action: action, Reason: reason})
+	}
+}
+
+// addOutsideOnly adds the branches which do not satisfy --only as kept, branches without a version can never satisfy it
+func (r *repoResult) addOutsideOnly(gitService *pkg.RemoteBranch, versions pkg.VersionParser, branches []string, only pkg.Constraint) {
+	r.add(gitService, versions, branches, actionKeep, "outside --only "+only.String())
+	r.set(pkg.Unversioned(branches, versions), actionKeep, "no version")
+}
+
+// set changes the action and reason of the branches
+func (r *repoResult) set(branches []string, action string, reason string) {
+	for _, b := range branches {
+		for i := range r.Branches {
+			if r.Branches[i].Name == b {
+				r.Branches[i].Action = action
+				r.Branches[i].Reason = reason
+			}
+		}
+	}
+}
+
+// setAction changes the action of the branches and keeps their reason
+func (r *repoResult) setAction(branches []string, action string) {
+	for _, b := range branches {
+		for i := range r.Branches {
+			if r.Branches[i].Name == b {
+				r.Branches[i].Action = action
+			}
+		}
+	}
+}
+
+// summary counts the failed repos and the branches per action
+func summary(results []*repoResult) string {
+	failed := 0
+	counts := map[string]int{}
+	for _, r := range results {
+		if r.Error != "" {
+			failed++
+		}
+		for _, b := range r.Branches {
+			counts[b.Action]++
+		}
+	}
+	s := fmt.Sprintf("%d repos processed, %d failed", len(results), failed)
+	var branches []string
+	for _, action := range []string{actionFound, actionLatest, actionKeep, actionDelete, actionDeleted, actionSkipped, actionFailed} {
+		if counts[action] > 0 {
+			branches = append(branches, fmt.Sprintf("%d %s", counts[action], action))
+		}
+	}
+	if len(branches) > 0 {
+		s += ", branches: " + strings.Join(branches, ", ")
+	}
+	return s
+}
+
+// anyFailed reports if one of the repos failed
+func anyFailed(results []*repoResult) bool {
+	for _, r := range results {
+		if r.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func validOutputFormat(format string) bool {
+	if format == "" {
+		return true
+	}
+	for _, f := range outputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// writeResults writes the results in the output format
+func writeResults(w io.Writer, format string, results []*repoResult) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(results)
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		return enc.Encode(results)
+	case "csv":
+		cw := csv.NewWriter(w)
+		_ = cw.Write([]string{"repo", "branch", "sha", "version", "action", "reason"})
+		for _, r := range results {
+			if r.Error != "" {
+				_ = cw.Write([]string{r.Repo, "", "", "", actionFailed, r.Error})
+			}
+			for _, b := range r.Branches {
+				_ = cw.Write([]string{r.Repo, b.Name, b.SHA, b.Version, b.Action, b.Reason})
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "REPO\tBRANCH\tSHA\tVERSION\tACTION\tREASON")
+		for _, r := range results {
+			if r.Error != "" {
+				fmt.Fprintf(tw, "%s\t\t\t\t%s\t%s\n", r.Repo, actionFailed, r.Error)
+			}
+			for _, b := range r.Branches {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", r.Repo, b.Name, b.SHA, b.Version, b.Action, b.Reason)
+			}
+		}
+		return tw.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testResults() []*repoResult {
+	return []*repoResult{{
+		Repo: "https://github.com/fhopfensperger/my-repo.git",
+		Branches: []branchResult{
+			{Name: "refs/heads/release/v1.0.0", SHA: "1111111111111111111111111111111111111111", Version: "v1.0.0", Action: actionDelete, Reason: "not kept by retention policy"},
+			{Name: "refs/heads/release/v1.0.1", SHA: "2222222222222222222222222222222222222222", Version: "v1.0.1", Action: actionKeep, Reason: "excluded by v1.0.1"},
+		},
+	}}
+}
+
+func Test_writeResults(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"", ""},
+		{"json", `[
+  {
+    "repo": "https://github.com/fhopfensperger/my-repo.git",
+    "branches": [
+      {
+        "name": "refs/heads/release/v1.0.0",
+        "sha": "1111111111111111111111111111111111111111",
+        "version": "v1.0.0",
+        "action": "delete",
+        "reason": "not kept by retention policy"
+      },
+      {
+        "name": "refs/heads/release/v1.0.1",
+        "sha": "2222222222222222222222222222222222222222",
+        "version": "v1.0.1",
+        "action": "keep",
+        "reason": "excluded by v1.0.1"
+      }
+    ]
+  }
+]
+`},
+		{"yaml", `- repo: https://github.com/fhopfensperger/my-repo.git
+  branches:
+    - name: refs/heads/release/v1.0.0
+      sha: "1111111111111111111111111111111111111111"
+      version: v1.0.0
+      action: delete
+      reason: not kept by retention policy
+    - name: refs/heads/release/v1.0.1
+      sha: "2222222222222222222222222222222222222222"
+      version: v1.0.1
+      action: keep
+      reason: excluded by v1.0.1
+`},
+		{"csv", `repo,branch,sha,version,action,reason
+https://github.com/fhopfensperger/my-repo.git,refs/heads/release/v1.0.0,1111111111111111111111111111111111111111,v1.0.0,delete,not kept by retention policy
+https://github.com/fhopfensperger/my-repo.git,refs/heads/release/v1.0.1,2222222222222222222222222222222222222222,v1.0.1,keep,excluded by v1.0.1
+`},
+		{"table", `REPO                                           BRANCH                     SHA                                       VERSION  ACTION  REASON
+https://github.com/fhopfensperger/my-repo.git  refs/heads/release/v1.0.0  1111111111111111111111111111111111111111  v1.0.0   delete  not kept by retention policy
+https://github.com/fhopfensperger/my-repo.git  refs/heads/release/v1.0.1  2222222222222222222222222222222222222222  v1.0.1   keep    excluded by v1.0.1
+`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			b := bytes.NewBufferString("")
+			assert.NoError(t, writeResults(b, tt.format, testResults()))
+			assert.Equal(t, tt.want, b.String())
+		})
+	}
+}
+
+func Test_writeResults_failed_repo(t *testing.T) {
+	result := newRepoResult("https://github.com/fhopfensperger/my-repo.git")
+	result.fail(zerolog.Nop(), errors.New("repository not found"))
+
+	b := bytes.NewBufferString("")
+	assert.NoError(t, writeResults(b, "csv", []*repoResult{result}))
+	assert.Equal(t, `repo,branch,sha,version,action,reason
+https://github.com/fhopfensperger/my-repo.git,,,,failed,repository not found
+`, b.String())
+}
+
+func Test_repoResult_set(t *testing.T) {
+	result := testResults()[0]
+	result.set([]string{"refs/heads/release/v1.0.1"}, actionSkipped, "changed since listing")
+	result.setAction([]string{"refs/heads/release/v1.0.0"}, actionDeleted)
+
+	assert.Equal(t, actionDeleted, result.Branches[0].Action)
+	assert.Equal(t, "not kept by retention policy", result.Branches[0].Reason)
+	assert.Equal(t, actionSkipped, result.Branches[1].Action)
+	assert.Equal(t, "changed since listing", result.Branches[1].Reason)
+}
+
+func Test_anyFailed(t *testing.T) {
+	results := testResults()
+	assert.False(t, anyFailed(results))
+	results = append(results, &repoResult{Repo: "/tmp/my-repo.git", Error: "repository not found"})
+	assert.True(t, anyFailed(results))
+}
+
+func Test_validOutputFormat(t *testing.T) {
+	assert.True(t, validOutputFormat(""))
+	assert.True(t, validOutputFormat("json"))
+	assert.False(t, validOutputFormat("xml"))
+}
+
+func Test_difference(t *testing.T) {
+	assert.Equal(t, []string{"a", "c"}, difference([]string{"a", "b", "c"}, []string{"b", "d"}))
+	assert.Empty(t, difference([]string{"a"}, []string{"a"}))
+}
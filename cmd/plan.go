@@ -0,0 +1,84 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/fhopfensperger/git-remote-cleanup/pkg"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// planCmd represents the plan command
+var planCmd = &cobra.Command{
+	Use:    "plan",
+	Short:  "Write the branches which would be deleted into a plan file",
+	Long:   `Write the exact branches which would be deleted into a plan file, which can be reviewed and executed by apply`,
+	PreRun: bindFlags,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkRepos()
+		checkFilter()
+		checkSelections()
+		plan := pkg.Plan{Created: time.Now().UTC()}
+		repoPlans := make([]*pkg.RepoPlan, len(repos))
+		results := processRepos(repos, func(i int, r string, auth transport.AuthMethod, logger zerolog.Logger) (*repoResult, error) {
+			sel, _ := readSelection(r)
+			gitService := pkg.New(nil, auth, append(sel.options(), pkg.WithLogger(logger))...)
+			branchesToDelete, result, err := selectBranches(&gitService, r, sel, logger)
+			if err != nil {
+				return result, err
+			}
+			branchesToDelete, err = excludeBranches(branchesToDelete, sel, result, logger)
+			if err != nil {
+				return result, err
+			}
+			repoPlan := gitService.PlanBranches(r, branchesToDelete)
+			repoPlans[i] = &repoPlan
+			return result, nil
+		})
+		for _, repoPlan := range repoPlans {
+			if repoPlan != nil {
+				plan.Repos = append(plan.Repos, *repoPlan)
+			}
+		}
+
+		planFile := viper.GetString("plan-file")
+		if err := pkg.WritePlan(planFile, plan); err != nil {
+			log.Err(err).Msgf("Could not write plan %s", planFile)
+			os.Exit(1)
+		}
+		log.Info().Msgf("Plan written to %s, execute it with: apply --plan-file %s", planFile, planFile)
+		if anyFailed(results) {
+			log.Error().Msgf("The plan does not contain the repos which failed, summary: %s", summary(results))
+			exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(planCmd)
+
+	flags := planCmd.Flags()
+	flags.String("plan-file", "", "File the plan is written to, e.g. plan.json")
+	_ = cobra.MarkFlagRequired(flags, "plan-file")
+	addSelectionFlags(flags)
+}
@@ -0,0 +1,64 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fhopfensperger/git-remote-cleanup/pkg"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:    "restore",
+	Short:  "Restore deleted branches from a backup bundle",
+	Long:   `Restore deleted branches from a backup bundle written by delete --backup-dir`,
+	PreRun: bindFlags,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkRepos()
+		if len(repos) != 1 {
+			fmt.Println("Exactly one repo must be set to restore a backup")
+			os.Exit(1)
+		}
+		auth, err := authFor(repos[0])
+		if err != nil {
+			log.Err(err).Msgf("Could not restore branches to repo %s", repos[0])
+			os.Exit(1)
+		}
+		gitService := pkg.New(nil, auth)
+		_, err = gitService.Restore(repos[0], viper.GetString("bundle"), viper.GetStringSlice("branches"), viper.GetBool("dry-run"))
+		completeAuth(auth, err, log.Logger)
+		if err != nil {
+			log.Err(err).Msgf("Could not restore branches to repo %s", repos[0])
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+
+	flags := restoreCmd.Flags()
+	flags.String("bundle", "", "Backup bundle written by delete --backup-dir")
+	_ = cobra.MarkFlagRequired(flags, "bundle")
+	flags.StringSlice("branches", []string{}, "Branches to restore, e.g. release/v1.0.1, all branches of the bundle if not set")
+	flags.Bool("dry-run", false, "Perform dry run, do not restore anything")
+}
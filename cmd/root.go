@@ -19,18 +19,32 @@ package cmd
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
+	"regexp"
+	"strings"
+	"time"
 
+	"github.com/fhopfensperger/git-remote-cleanup/pkg"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/spf13/viper"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
 var repos []string
 var filter string
 var fileName string
 var pat string
+var output string
+
+// logOutput is the destination of the logs, stderr if an output format is set
+var logOutput io.Writer = os.Stdout
+
+// exit ends the process with the status code, tests replace it to keep running
+var exit = os.Exit
 
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
@@ -53,28 +67,97 @@ func init() {
 	cobra.OnInitialize(initConfig)
 
 	pf := rootCmd.PersistentFlags()
+	pf.String("config", "", "Config file (yaml or toml) with the defaults of all flags and the repos, see README")
+	_ = viper.BindPFlag("config", pf.Lookup("config"))
+
 	pf.StringSliceP("repos", "r", []string{}, "Git Repo urls e.g. git@github.com:fhopfensperger/my-repo.git")
 	_ = viper.BindPFlag("repos", pf.Lookup("repos"))
 
 	pf.StringP("filter", "b", "", "Which branches should be filtered e.g. release")
 	_ = viper.BindPFlag("filter", pf.Lookup("filter"))
 	_ = cobra.MarkFlagRequired(pf, "branch-filter")
+	pf.StringArray("filter-regex", []string{}, "Which branches should be filtered by a regex, can be repeated e.g. ^release/v")
+	_ = viper.BindPFlag("filter-regex", pf.Lookup("filter-regex"))
+	pf.StringArray("filter-glob", []string{}, "Which branches should be filtered by a glob, can be repeated e.g. release/v*")
+	_ = viper.BindPFlag("filter-glob", pf.Lookup("filter-glob"))
+
+	pf.String("version-scheme", "semver", "Versions of the branches: semver (e.g. v1.2.3 or 1.2.3.4) or calver (e.g. 2024.03 or 2024.11.1)")
+	_ = viper.BindPFlag("version-scheme", pf.Lookup("version-scheme"))
+	pf.String("group-by", "", "Regex with a capture group for the component of a branch, the retention policy and --latest apply per component, e.g. ^release/([^/]+)/")
+	_ = viper.BindPFlag("group-by", pf.Lookup("group-by"))
 
 	pf.StringP("file", "f", "", "Uses repos from file (one repo per line)")
 	_ = viper.BindPFlag("file", pf.Lookup("file"))
 	pf.StringP("pat", "p", "", `Use a Git Personal Access Token instead of the default private certificate! You could also set a environment variable. "export PAT=123456789" `)
 	_ = viper.BindPFlag("pat", pf.Lookup("pat"))
 
+	pf.StringP("output", "o", "", "Write the results to stdout as json, yaml, csv or table, logs are written to stderr then")
+	_ = viper.BindPFlag("output", pf.Lookup("output"))
+
+	addAuthFlags(pf)
+
+	pf.Int("concurrency", 1, "Number of repos processed in parallel")
+	_ = viper.BindPFlag("concurrency", pf.Lookup("concurrency"))
+
 	rootCmd.SetVersionTemplate(`{{printf "v%s\n" .Version}}`)
 }
 
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
-	viper.AutomaticEnv() // read in environment variables that match
-	repos = viper.GetStringSlice("repos")
+	viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_")) // e.g. KEEP_PATCHES for --keep-patches
+	viper.AutomaticEnv()                                   // read in environment variables that match
+	if configFile := viper.GetString("config"); configFile != "" {
+		viper.SetConfigFile(configFile)
+		if err := viper.ReadInConfig(); err != nil {
+			fmt.Printf("Could not read config %s: %v\n", configFile, err)
+			os.Exit(1)
+		}
+	}
+	var err error
+	if repos, repoOverrides, err = parseRepos(viper.Get("repos")); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 	filter = viper.GetString("filter")
 	fileName = viper.GetString("file")
 	pat = viper.GetString("pat")
+	output = viper.GetString("output")
+	if !validOutputFormat(output) {
+		fmt.Printf("Invalid output format %s, use one of %v\n", output, outputFormats)
+		os.Exit(1)
+	}
+	if file := viper.GetString("credentials"); file != "" {
+		if credentials, err = readCredentials(file); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+	}
+	if output != "" {
+		// Keep stdout for the results
+		logOutput = os.Stderr
+		log.Logger = log.Output(zerolog.ConsoleWriter{Out: logOutput, TimeFormat: time.RFC3339})
+	}
+}
+
+// writeOutput logs the summary of the results and writes the results to stdout, if an output format is set. It exits
+// with status 1 if a repo failed, so failures are detected without parsing the logs.
+func writeOutput(cmd *cobra.Command, results []*repoResult) {
+	log.Info().Msgf("Summary: %s", summary(results))
+	if err := writeResults(cmd.OutOrStdout(), output, results); err != nil {
+		log.Err(err).Msg("Could not write the results")
+		os.Exit(1)
+	}
+	if anyFailed(results) {
+		exit(1)
+	}
+}
+
+// bindFlags binds the flags of the executed command to viper. Commands share flag names like dry-run, so they are bound
+// when it is known which command is executed.
+func bindFlags(cmd *cobra.Command, args []string) {
+	cmd.LocalNonPersistentFlags().VisitAll(func(f *pflag.Flag) {
+		_ = viper.BindPFlag(f.Name, f)
+	})
 }
 
 func getReposFromFile(fileName string) []string {
@@ -98,6 +181,66 @@ func getReposFromFile(fileName string) []string {
 	return lines
 }
 
+// checkFilter exits if no branch filter is set for a repo or a filter is invalid
+func checkFilter() {
+	for _, r := range repos {
+		filters, err := filtersFor(repoConfig(r))
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		if len(filters) == 0 {
+			fmt.Println("-b (filter), --filter-regex or --filter-glob must be set")
+			os.Exit(1)
+		}
+	}
+}
+
+// filtersFor returns the branch filters of -b, --filter-regex and --filter-glob, a branch must match one of them
+func filtersFor(v *viper.Viper) ([]pkg.Filter, error) {
+	var filters []pkg.Filter
+	if f := v.GetString("filter"); f != "" {
+		filters = append(filters, pkg.SubstringFilter(f))
+	}
+	for _, expr := range v.GetStringSlice("filter-regex") {
+		f, err := pkg.RegexFilter(expr)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	for _, pattern := range v.GetStringSlice("filter-glob") {
+		f, err := pkg.GlobFilter(pattern)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+// versionParserFor returns the parser of the --version-scheme
+func versionParserFor(v *viper.Viper) (pkg.VersionParser, error) {
+	parser, err := pkg.ParseVersionScheme(v.GetString("version-scheme"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid --version-scheme: %w", err)
+	}
+	return parser, nil
+}
+
+// groupByFor returns the regex of --group-by, nil if it is not set
+func groupByFor(v *viper.Viper) (*regexp.Regexp, error) {
+	expr := v.GetString("group-by")
+	if expr == "" {
+		return nil, nil
+	}
+	groupBy, err := pkg.ParseGroupBy(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --group-by: %w", err)
+	}
+	return groupBy, nil
+}
+
 func checkRepos() {
 	if fileName != "" {
 		repos = getReposFromFile(fileName)
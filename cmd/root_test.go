@@ -61,6 +61,9 @@ func TestExecute_version(t *testing.T) {
 }
 
 func TestExecute_repos_from_args(t *testing.T) {
+	exitCode := 0
+	exit = func(code int) { exitCode = code }
+	defer func() { exit = os.Exit }()
 	cmd := rootCmd
 	testRepos := []string{"git@github.com:fhopfensperger/my-repo.git"}
 	cmd.SetArgs([]string{"branches", "-b", "release", "-r", testRepos[0]})
@@ -68,9 +71,14 @@ func TestExecute_repos_from_args(t *testing.T) {
 
 	assert.Equal(t, repos, testRepos)
 	assert.Equal(t, filter, "release")
+	// The repo does not exist, a failed repo sets the exit status
+	assert.Equal(t, 1, exitCode)
 }
 
 func TestExecute_repos_from_file(t *testing.T) {
+	exitCode := 0
+	exit = func(code int) { exitCode = code }
+	defer func() { exit = os.Exit }()
 	repo1 := "git@github.com:fhopfensperger/my-repo.git"
 	fileName := "test.txt"
 	f, _ := os.Create(fileName)
@@ -82,6 +90,7 @@ func TestExecute_repos_from_file(t *testing.T) {
 
 	assert.Equal(t, repos, []string{repo1})
 	assert.Equal(t, filter, "release")
+	assert.Equal(t, 1, exitCode)
 	os.Remove(fileName)
 }
 
@@ -102,6 +111,9 @@ func TestExecute_repos_or_file_must_be_defined(t *testing.T) {
 }
 
 func TestExecute_delete_exclude_dry_run(t *testing.T) {
+	exitCode := 0
+	exit = func(code int) { exitCode = code }
+	defer func() { exit = os.Exit }()
 	repo1 := "git@github.com:fhopfensperger/my-repo.git"
 	fileName := "test.txt"
 	f, _ := os.Create(fileName)
@@ -114,7 +126,10 @@ func TestExecute_delete_exclude_dry_run(t *testing.T) {
 		fileName, "--dry-run", "-e", excludeList[0] + "," + excludeList[1]})
 	Execute("0.0.0")
 
-	assert.Equal(t, excludeList, excludes)
+	sel, err := readSelection(repo1)
+	assert.NoError(t, err)
+	assert.Equal(t, excludeList, sel.excludes)
 	assert.Equal(t, true, dryRun)
+	assert.Equal(t, 1, exitCode)
 	os.Remove(fileName)
 }
@@ -0,0 +1,40 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// tagCmd represents the tags command
+var tagCmd = &cobra.Command{
+	Use:    "tags",
+	Short:  "Get remote tags",
+	Long:   `Get remote tags, filtered and sorted by version like branches`,
+	PreRun: bindFlags,
+	Run: func(cmd *cobra.Command, args []string) {
+		listRefs(cmd, true)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+
+	flags := tagCmd.Flags()
+	flags.BoolP("latest", "l", false, "Print latest remote tag for filter, per component with --group-by")
+	flags.String("only", "", "Only get tags whose version satisfies the semver range, e.g. '<2.0.0'")
+}
@@ -0,0 +1,92 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+)
+
+// processFunc processes the repo with the authentication of the repo, the error is recorded in the result
+type processFunc func(i int, repo string, auth transport.AuthMethod, logger zerolog.Logger) (*repoResult, error)
+
+// processRepos processes up to --concurrency repos in parallel and returns their results in the order of the repos.
+// Every repo gets its own logger, whose logs are written as one group once the repo and all repos before it are done.
+func processRepos(repos []string, process processFunc) []*repoResult {
+	results := make([]*repoResult, len(repos))
+	concurrency := viper.GetInt("concurrency")
+	if concurrency <= 1 {
+		for i, r := range repos {
+			results[i] = processRepo(i, r, newLogger(logOutput), process)
+		}
+		return results
+	}
+
+	logs := make([]bytes.Buffer, len(repos))
+	done := make([]chan struct{}, len(repos))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+	jobs := make(chan int)
+	for w := 0; w < concurrency; w++ {
+		go func() {
+			for i := range jobs {
+				results[i] = processRepo(i, repos[i], newLogger(&logs[i]), process)
+				close(done[i])
+			}
+		}()
+	}
+	go func() {
+		for i := range repos {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+
+	for i := range repos {
+		<-done[i]
+		_, _ = io.Copy(logOutput, &logs[i])
+	}
+	return results
+}
+
+// processRepo processes the repo with its authentication and records the error in the result
+func processRepo(i int, repo string, logger zerolog.Logger, process processFunc) *repoResult {
+	var result *repoResult
+	auth, err := authFor(repo)
+	if err == nil {
+		result, err = process(i, repo, auth, logger)
+		completeAuth(auth, err, logger)
+	}
+	if result == nil {
+		result = newRepoResult(repo)
+	}
+	if err != nil {
+		result.fail(logger, err)
+	}
+	return result
+}
+
+// newLogger returns a logger writing to w in the same format as the global logger
+func newLogger(w io.Writer) zerolog.Logger {
+	return zerolog.New(zerolog.ConsoleWriter{Out: w, TimeFormat: time.RFC3339}).With().Timestamp().Logger()
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/rs/zerolog"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_processRepos(t *testing.T) {
+	for _, concurrency := range []int{1, 3} {
+		viper.Set("concurrency", concurrency)
+		var logs bytes.Buffer
+		logOutput = &logs
+
+		repos := []string{"repo-a", "repo-b", "repo-c", "repo-d"}
+		results := processRepos(repos, func(i int, repo string, _ transport.AuthMethod, logger zerolog.Logger) (*repoResult, error) {
+			// The first repos finish last
+			time.Sleep(time.Duration(len(repos)-i) * 10 * time.Millisecond)
+			logger.Info().Msgf("start %s", repo)
+			logger.Info().Msgf("end %s", repo)
+			return newRepoResult(repo), nil
+		})
+
+		var got []string
+		for _, r := range results {
+			got = append(got, r.Repo)
+		}
+		assert.Equal(t, repos, got)
+		// The logs of every repo are grouped in the order of the repos
+		last := -1
+		for _, repo := range repos {
+			for _, msg := range []string{"start " + repo, "end " + repo} {
+				pos := strings.Index(logs.String(), msg)
+				assert.Greater(t, pos, last, msg)
+				last = pos
+			}
+		}
+	}
+	viper.Set("concurrency", 1)
+	logOutput = os.Stdout
+}
+
+func Test_summary(t *testing.T) {
+	failed := newRepoResult("https://github.com/fhopfensperger/other-repo.git")
+	failed.Error = "repository not found"
+	results := append(testResults(), failed)
+
+	assert.Equal(t, "2 repos processed, 1 failed, branches: 1 keep, 1 delete", summary(results))
+}
+
+func Test_processRepos_error(t *testing.T) {
+	results := processRepos([]string{"/tmp/my-repo.git"}, func(_ int, repo string, _ transport.AuthMethod, _ zerolog.Logger) (*repoResult, error) {
+		return nil, errors.New("repository not found")
+	})
+
+	assert.Equal(t, "/tmp/my-repo.git", results[0].Repo)
+	assert.Equal(t, "repository not found", results[0].Error)
+}
@@ -0,0 +1,91 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseAge parses a duration like time.ParseDuration, additionally supporting days and weeks, e.g. 180d or 2w
+func ParseAge(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if strings.HasSuffix(s, suffix) {
+			n, err := strconv.Atoi(strings.TrimSuffix(s, suffix))
+			if err != nil || n < 0 {
+				return 0, fmt.Errorf("invalid age %q", s)
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// FilterBranchesByAge adjusts the branches to delete, which were selected by FilterBranches, using the last commit
+// dates of the branches. Branches older than policy.OlderThan are deleted regardless of their version, branches newer
+// than policy.KeepNewerThan are always kept. Branches without a commit date are not changed, branches without a
+// version are only deleted with policy.DeleteUnversioned.
+func FilterBranchesByAge(branches []string, branchesToDelete []string, commitDates map[string]time.Time, policy RetentionPolicy, now time.Time) []string {
+	sortByVersion(branches, policy.Versions)
+	unversioned := toSet(Unversioned(branches, policy.Versions))
+	newest := toSet(LatestPerComponent(branches, policy.GroupBy))
+
+	toDelete := map[string]bool{}
+	for _, b := range branchesToDelete {
+		toDelete[b] = true
+	}
+
+	filteredBranches := make([]string, 0, len(branches))
+	for _, b := range branches {
+		date, ok := commitDates[b]
+		if ok && policy.OlderThan > 0 && now.Sub(date) > policy.OlderThan {
+			toDelete[b] = true
+		}
+		if ok && policy.KeepNewerThan > 0 && now.Sub(date) <= policy.KeepNewerThan {
+			toDelete[b] = false
+		}
+		if policy.KeepNewest && newest[b] || unversioned[b] && !policy.DeleteUnversioned {
+			toDelete[b] = false
+		}
+		if toDelete[b] {
+			filteredBranches = append(filteredBranches, b)
+		}
+	}
+	return filteredBranches
+}
+
+// StaleBranches returns the branches whose last commit is older than olderThan, regardless of their version, e.g.
+// forgotten feature branches. Branches without a commit date are not stale. The order of the branches is kept.
+func StaleBranches(branches []string, commitDates map[string]time.Time, olderThan time.Duration, now time.Time) []string {
+	var stale []string
+	for _, b := range branches {
+		if date, ok := commitDates[b]; ok && now.Sub(date) > olderThan {
+			stale = append(stale, b)
+		}
+	}
+	return stale
+}
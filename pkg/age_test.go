@@ -0,0 +1,112 @@
+package pkg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		name    string
+		age     string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"empty", "", 0, false},
+		{"days", "180d", 180 * 24 * time.Hour, false},
+		{"weeks", "2w", 14 * 24 * time.Hour, false},
+		{"hours", "12h", 12 * time.Hour, false},
+		{"invalid-days", "xd", 0, true},
+		{"negative", "-1h", 0, true},
+		{"invalid", "half a year", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAge(tt.age)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
+
+func TestFilterBranchesByAge(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	branches := []string{"head/release/v1.0.0", "head/release/v1.0.1", "head/release/v1.1.0", "head/release/v2.0.0"}
+	commitDates := map[string]time.Time{
+		"head/release/v1.0.0": now.AddDate(0, 0, -300),
+		"head/release/v1.0.1": now.AddDate(0, 0, -10),
+		"head/release/v1.1.0": now.AddDate(0, 0, -200),
+		"head/release/v2.0.0": now.AddDate(0, 0, -400),
+	}
+	tests := []struct {
+		name             string
+		branchesToDelete []string
+		commitDates      map[string]time.Time
+		policy           RetentionPolicy
+		want             []string
+	}{
+		{
+			name:             "delete-older-than",
+			branchesToDelete: []string{"head/release/v1.0.0"},
+			commitDates:      commitDates,
+			policy:           RetentionPolicy{OlderThan: 180 * 24 * time.Hour},
+			want:             []string{"head/release/v1.0.0", "head/release/v1.1.0", "head/release/v2.0.0"},
+		},
+		{
+			name:             "delete-older-than-keep-newest",
+			branchesToDelete: []string{"head/release/v1.0.0"},
+			commitDates:      commitDates,
+			policy:           RetentionPolicy{OlderThan: 180 * 24 * time.Hour, KeepNewest: true},
+			want:             []string{"head/release/v1.0.0", "head/release/v1.1.0"},
+		},
+		{
+			name:             "keep-newer-than",
+			branchesToDelete: []string{"head/release/v1.0.0", "head/release/v1.0.1"},
+			commitDates:      commitDates,
+			policy:           RetentionPolicy{KeepNewerThan: 30 * 24 * time.Hour},
+			want:             []string{"head/release/v1.0.0"},
+		},
+		{
+			name:             "missing-commit-date",
+			branchesToDelete: []string{"head/release/v1.0.0"},
+			commitDates:      map[string]time.Time{"head/release/v1.1.0": commitDates["head/release/v1.1.0"], "head/release/v2.0.0": commitDates["head/release/v2.0.0"]},
+			policy:           RetentionPolicy{OlderThan: 180 * 24 * time.Hour},
+			want:             []string{"head/release/v1.0.0", "head/release/v1.1.0", "head/release/v2.0.0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FilterBranchesByAge(append([]string{}, branches...), tt.branchesToDelete, tt.commitDates, tt.policy, now))
+		})
+	}
+}
+
+func TestFilterBranchesByAge_unversioned(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	branches := []string{"head/release/next", "head/release/v1.0.0", "head/release/v1.0.1"}
+	commitDates := map[string]time.Time{
+		"head/release/next":   now.AddDate(0, 0, -300),
+		"head/release/v1.0.0": now.AddDate(0, 0, -300),
+		"head/release/v1.0.1": now.AddDate(0, 0, -300),
+	}
+	policy := RetentionPolicy{OlderThan: 180 * 24 * time.Hour, KeepNewest: true}
+
+	assert.Equal(t, []string{"head/release/v1.0.0"}, FilterBranchesByAge(append([]string{}, branches...), nil, commitDates, policy, now))
+	policy.DeleteUnversioned = true
+	assert.Equal(t, []string{"head/release/next", "head/release/v1.0.0"}, FilterBranchesByAge(append([]string{}, branches...), nil, commitDates, policy, now))
+}
+
+func TestStaleBranches(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	branches := []string{"refs/heads/feature/login", "refs/heads/bugfix/crash", "refs/heads/feature/v2-api", "refs/heads/feature/new"}
+	commitDates := map[string]time.Time{
+		"refs/heads/feature/login":  now.AddDate(0, 0, -120),
+		"refs/heads/bugfix/crash":   now.AddDate(0, 0, -30),
+		"refs/heads/feature/v2-api": now.AddDate(0, 0, -91),
+	}
+
+	got := StaleBranches(branches, commitDates, 90*24*time.Hour, now)
+	assert.Equal(t, []string{"refs/heads/feature/login", "refs/heads/feature/v2-api"}, got)
+}
@@ -0,0 +1,213 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/format/packfile"
+	"github.com/go-git/go-git/v5/plumbing/revlist"
+	"github.com/go-git/go-git/v5/plumbing/storer"
+)
+
+const bundleSignature = "# v2 git bundle"
+
+// Backup fetches the complete history of the branches and writes it as git bundle into the directory, it returns the
+// path of the bundle. The bundle can be used by Restore or by git itself, e.g. git clone <bundle>.
+func (m *RemoteBranch) Backup(branches []string, dir string) (string, error) {
+	var refspecs []config.RefSpec
+	var tips []plumbing.Hash
+	for _, b := range branches {
+		hash, ok := m.hashes[b]
+		if !ok {
+			return "", fmt.Errorf("unknown tip commit of branch %s", b)
+		}
+		refspecs = append(refspecs, config.RefSpec("+"+b+":"+fetchedRef(b)))
+		tips = append(tips, hash)
+	}
+
+	err := m.gitClient.Fetch(&git.FetchOptions{
+		RefSpecs: refspecs,
+		Depth:    unshallowDepth,
+		Tags:     git.NoTags,
+		Auth:     m.auth,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return "", m.wrapError(err)
+	}
+
+	objects, err := revlist.Objects(m.storage, tips, nil)
+	if err != nil {
+		return "", fmt.Errorf("incomplete history of branches %v: %w", branches, err)
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	file, err := createBundle(dir, m.gitClient.Config().URLs[0], time.Now())
+	if err != nil {
+		return "", err
+	}
+	if err := writeBundle(file, branches, tips, m.storage, objects); err != nil {
+		_ = os.Remove(file.Name())
+		return "", fmt.Errorf("could not write bundle %s: %w", file.Name(), err)
+	}
+
+	m.logger.Info().Msgf("Backup of branches %v written to %s", branches, file.Name())
+	return file.Name(), nil
+}
+
+// createBundle creates a new bundle file for the repo in the directory, an existing file is never overwritten. The name
+// contains a hash of the repo url, so repos with the same name on other hosts or paths get their own bundles, e.g.
+// my-repo-1a2b3c4d-20240101T120000Z.bundle.
+func createBundle(dir string, repoURL string, now time.Time) (*os.File, error) {
+	hash := sha256.Sum256([]byte(repoURL))
+	prefix := fmt.Sprintf("%s-%x-%s", path.Base(strings.TrimSuffix(repoURL, ".git")), hash[:4], now.UTC().Format("20060102T150405Z"))
+	for i := 0; ; i++ {
+		name := prefix + ".bundle"
+		if i > 0 {
+			name = fmt.Sprintf("%s-%d.bundle", prefix, i)
+		}
+		file, err := os.OpenFile(filepath.Join(dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if !errors.Is(err, fs.ErrExist) {
+			return file, err
+		}
+	}
+}
+
+// writeBundle writes the branches with their tips and the objects as bundle into the file and closes it
+func writeBundle(file *os.File, branches []string, tips []plumbing.Hash, s storer.EncodedObjectStorer, objects []plumbing.Hash) error {
+	w := bufio.NewWriter(file)
+	fmt.Fprintln(w, bundleSignature)
+	for i, b := range branches {
+		fmt.Fprintf(w, "%s %s\n", tips[i], b)
+	}
+	fmt.Fprintln(w)
+	if _, err := packfile.NewEncoder(w, s, false).Encode(objects, 10); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := w.Flush(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Restore pushes the branches from a bundle written by Backup to the remote repo. If branches is empty, all branches
+// of the bundle are restored, otherwise only the branches with the given full or short names.
+func (m *RemoteBranch) Restore(repoURL string, bundleFile string, branches []string, dryRun bool) ([]string, error) {
+	refs, err := m.readBundle(bundleFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var restore []*plumbing.Reference
+	for _, ref := range refs {
+		if len(branches) == 0 {
+			restore = append(restore, ref)
+			continue
+		}
+		for _, b := range branches {
+			if b == ref.Name().String() || b == ref.Name().Short() {
+				restore = append(restore, ref)
+			}
+		}
+	}
+	if len(restore) == 0 {
+		return nil, fmt.Errorf("no branches %v found in bundle %s", branches, bundleFile)
+	}
+
+	m.initClient(repoURL)
+
+	var restoredBranches []string
+	var refspecs []config.RefSpec
+	for _, ref := range restore {
+		if err := m.storage.SetReference(ref); err != nil {
+			return nil, err
+		}
+		restoredBranches = append(restoredBranches, ref.Name().String())
+		refspecs = append(refspecs, config.RefSpec(ref.Name()+":"+ref.Name()))
+	}
+
+	m.logger.Info().Msgf("Going to restore branches: %v to repo %s", restoredBranches, repoURL)
+	if dryRun {
+		m.logger.Info().Msg("Dry run! Nothing restored")
+		return nil, nil
+	}
+	err = m.gitClient.Push(&git.PushOptions{
+		RefSpecs: refspecs,
+		Auth:     m.auth,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return nil, wrapPushError(repoURL, err)
+	}
+	m.logger.Info().Msg("Branches restored")
+	return restoredBranches, nil
+}
+
+// readBundle reads the objects of the bundle into the storage and returns the references of the bundle
+func (m *RemoteBranch) readBundle(bundleFile string) ([]*plumbing.Reference, error) {
+	file, err := os.Open(bundleFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	signature, err := r.ReadString('\n')
+	if err != nil || strings.TrimSpace(signature) != bundleSignature {
+		return nil, fmt.Errorf("%s is not a v2 git bundle", bundleFile)
+	}
+
+	var refs []*plumbing.Reference
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("invalid bundle %s: %w", bundleFile, err)
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "-") {
+			return nil, fmt.Errorf("bundle %s with prerequisites is not supported", bundleFile)
+		}
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("invalid reference %q in bundle %s", line, bundleFile)
+		}
+		refs = append(refs, plumbing.NewReferenceFromStrings(fields[1], fields[0]))
+	}
+
+	if err := packfile.UpdateObjectStorage(m.storage, r); err != nil {
+		return nil, err
+	}
+	return refs, nil
+}
@@ -0,0 +1,126 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRemoteBranch_Backup_and_Restore(t *testing.T) {
+	dir := t.TempDir()
+	remoteConfig := &config.RemoteConfig{
+		Name: "amqp-sb-client.git",
+		URLs: []string{"https://github.com/fhopfensperger/amqp-sb-client.git"},
+	}
+
+	remote := new(remoteBranchMock)
+	backupRemoteBranch := New(remote, nil)
+	date := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	first := storeCommit(backupRemoteBranch.storage, date)
+	second := storeCommit(backupRemoteBranch.storage, date.AddDate(0, 0, 1), first)
+	remote.On("List", &git.ListOptions{}).Return([]*plumbing.Reference{
+		plumbing.NewHashReference("refs/heads/release/v1.0.0", first),
+		plumbing.NewHashReference("refs/heads/release/v1.0.1", second),
+	}, nil)
+	remote.On("Fetch", mock.Anything).Return(nil)
+	remote.On("Config").Return(remoteConfig)
+
+	branches, err := backupRemoteBranch.GetRemoteBranches("https://github.com/fhopfensperger/amqp-sb-client.git", "release", false)
+	assert.NoError(t, err)
+	bundle, err := backupRemoteBranch.Backup(branches, dir)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(filepath.Base(bundle), "amqp-sb-client-"))
+
+	content, _ := os.ReadFile(bundle)
+	assert.True(t, strings.HasPrefix(string(content), "# v2 git bundle\n"+
+		first.String()+" refs/heads/release/v1.0.0\n"+
+		second.String()+" refs/heads/release/v1.0.1\n\nPACK"))
+
+	restoreRemote := new(remoteBranchMock)
+	restoreRemoteBranch := New(restoreRemote, nil)
+	restored, err := restoreRemoteBranch.Restore("https://github.com/fhopfensperger/amqp-sb-client.git", bundle, []string{"release/v1.0.1"}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"refs/heads/release/v1.0.1"}, restored)
+	assert.Equal(t, []config.RefSpec{"refs/heads/release/v1.0.1:refs/heads/release/v1.0.1"}, restoreRemote.pushOptions.RefSpecs)
+
+	// The complete history must be restored from the bundle
+	commit, err := object.GetCommit(restoreRemoteBranch.storage, second)
+	assert.NoError(t, err)
+	_, err = commit.Parent(0)
+	assert.NoError(t, err)
+}
+
+func TestRemoteBranch_Backup_same_repo_name(t *testing.T) {
+	dir := t.TempDir()
+	var bundles []string
+	for _, repoURL := range []string{"/tmp/t2/a/api.git", "/tmp/t2/b/api.git"} {
+		remote := new(remoteBranchMock)
+		mockRemoteBranch := New(remote, nil)
+		tip := storeCommit(mockRemoteBranch.storage, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+		remote.On("List", &git.ListOptions{}).Return([]*plumbing.Reference{plumbing.NewHashReference("refs/heads/release/v1.0.0", tip)}, nil)
+		remote.On("Fetch", mock.Anything).Return(nil)
+		remote.On("Config").Return(&config.RemoteConfig{Name: "api.git", URLs: []string{repoURL}})
+
+		branches, err := mockRemoteBranch.GetRemoteBranches(repoURL, "release", false)
+		assert.NoError(t, err)
+		bundle, err := mockRemoteBranch.Backup(branches, dir)
+		assert.NoError(t, err)
+		assert.True(t, strings.HasPrefix(filepath.Base(bundle), "api-"))
+		bundles = append(bundles, bundle)
+	}
+
+	assert.NotEqual(t, bundles[0], bundles[1])
+	entries, _ := os.ReadDir(dir)
+	assert.Len(t, entries, 2)
+}
+
+func Test_createBundle_never_overwrites(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	first, err := createBundle(dir, "https://github.com/fhopfensperger/my-repo.git", now)
+	assert.NoError(t, err)
+	_, _ = first.WriteString("first")
+	_ = first.Close()
+
+	second, err := createBundle(dir, "https://github.com/fhopfensperger/my-repo.git", now)
+	assert.NoError(t, err)
+	_ = second.Close()
+
+	assert.NotEqual(t, first.Name(), second.Name())
+	assert.True(t, strings.HasSuffix(second.Name(), "-20240101T120000Z-1.bundle"))
+	content, _ := os.ReadFile(first.Name())
+	assert.Equal(t, "first", string(content))
+}
+
+func TestRemoteBranch_Backup_incomplete_history(t *testing.T) {
+	remote := new(remoteBranchMock)
+	mockRemoteBranch := New(remote, nil)
+	missing := plumbing.NewHash("1111111111111111111111111111111111111111")
+	remote.On("List", &git.ListOptions{}).Return([]*plumbing.Reference{
+		plumbing.NewHashReference("refs/heads/release/v1.0.0", storeCommit(mockRemoteBranch.storage, time.Now(), missing)),
+	}, nil)
+	remote.On("Fetch", mock.Anything).Return(nil)
+
+	branches, err := mockRemoteBranch.GetRemoteBranches("https://github.com/fhopfensperger/amqp-sb-client.git", "release", false)
+	assert.NoError(t, err)
+	_, err = mockRemoteBranch.Backup(branches, t.TempDir())
+	assert.Error(t, err)
+}
+
+func TestRemoteBranch_Restore_invalid_bundle(t *testing.T) {
+	bundle := filepath.Join(t.TempDir(), "invalid.bundle")
+	_ = os.WriteFile(bundle, []byte("no bundle"), 0o644)
+
+	mockRemoteBranch := New(new(remoteBranchMock), nil)
+	_, err := mockRemoteBranch.Restore("https://github.com/fhopfensperger/amqp-sb-client.git", bundle, nil, false)
+	assert.Error(t, err)
+}
@@ -0,0 +1,85 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// ParseGroupBy compiles the regex which groups the branches by component, e.g. ^release/([^/]+)/ groups
+// release/api/v1.2.0 and release/web/v1.2.0 into the components api and web. The component is the capture group named
+// component, or the first capture group.
+func ParseGroupBy(expr string) (*regexp.Regexp, error) {
+	regex, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid group by regex %q: %w", expr, err)
+	}
+	if regex.NumSubexp() == 0 {
+		return nil, fmt.Errorf("invalid group by regex %q: a capture group for the component is missing", expr)
+	}
+	return regex, nil
+}
+
+// Component returns the component of the branch by the short name of the branch, branches which do not match the
+// regex and all branches if the regex is nil have the empty component
+func Component(branch string, groupBy *regexp.Regexp) string {
+	if groupBy == nil {
+		return ""
+	}
+	match := groupBy.FindStringSubmatch(plumbing.ReferenceName(branch).Short())
+	if match == nil {
+		return ""
+	}
+	if i := groupBy.SubexpIndex("component"); i > 0 {
+		return match[i]
+	}
+	return match[1]
+}
+
+// GroupByComponent groups the branches by their component, the groups are sorted by component and keep the order of
+// the branches
+func GroupByComponent(branches []string, groupBy *regexp.Regexp) [][]string {
+	groups := map[string][]string{}
+	var components []string
+	for _, b := range branches {
+		c := Component(b, groupBy)
+		if _, ok := groups[c]; !ok {
+			components = append(components, c)
+		}
+		groups[c] = append(groups[c], b)
+	}
+	sort.Strings(components)
+
+	grouped := make([][]string, 0, len(components))
+	for _, c := range components {
+		grouped = append(grouped, groups[c])
+	}
+	return grouped
+}
+
+// LatestPerComponent returns the last branch of every component of the sorted branches
+func LatestPerComponent(branches []string, groupBy *regexp.Regexp) []string {
+	var latest []string
+	for _, group := range GroupByComponent(branches, groupBy) {
+		latest = append(latest, group[len(group)-1])
+	}
+	return latest
+}
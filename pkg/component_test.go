@@ -0,0 +1,55 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGroupBy(t *testing.T) {
+	_, err := ParseGroupBy("^release/([^/]+)/")
+	assert.NoError(t, err)
+	_, err = ParseGroupBy("^release/[^/]+/")
+	assert.Error(t, err)
+	_, err = ParseGroupBy("^release/(")
+	assert.Error(t, err)
+}
+
+func TestComponent(t *testing.T) {
+	groupBy, _ := ParseGroupBy("^release/([^/]+)/")
+	assert.Equal(t, "api", Component("refs/heads/release/api/v1.2.0", groupBy))
+	assert.Equal(t, "", Component("refs/heads/release/v1.2.0", groupBy))
+	assert.Equal(t, "", Component("refs/heads/release/api/v1.2.0", nil))
+
+	named, _ := ParseGroupBy("^(release|hotfix)/(?P<component>[^/]+)/")
+	assert.Equal(t, "web", Component("refs/heads/hotfix/web/v1.2.0", named))
+}
+
+func TestFilterBranches_group_by(t *testing.T) {
+	groupBy, _ := ParseGroupBy("^release/([^/]+)/")
+	branches := []string{"refs/heads/release/api/v1.2.0", "refs/heads/release/web/v1.2.0", "refs/heads/release/api/v1.2.1",
+		"refs/heads/release/web/v1.3.0", "refs/heads/release/web/v1.3.1"}
+
+	assert.Equal(t, []string{"refs/heads/release/api/v1.2.0", "refs/heads/release/web/v1.3.0"},
+		FilterBranches(branches, RetentionPolicy{Patches: 1, KeepNewest: true, GroupBy: groupBy}))
+	assert.Equal(t, []string{"refs/heads/release/api/v1.2.1", "refs/heads/release/web/v1.3.1"},
+		LatestPerComponent(branches, groupBy))
+}
+
+func TestGetRemoteBranchesMatching_latest_per_component(t *testing.T) {
+	groupBy, _ := ParseGroupBy("^release/([^/]+)/")
+	remote := new(remoteBranchMock)
+	mockRemoteBranch := New(remote, nil, WithGroupBy(groupBy))
+	remote.On("List", &git.ListOptions{}).Return([]*plumbing.Reference{
+		plumbing.NewHashReference("refs/heads/release/web/v1.3.0", plumbing.ZeroHash),
+		plumbing.NewHashReference("refs/heads/release/api/v1.2.0", plumbing.ZeroHash),
+		plumbing.NewHashReference("refs/heads/release/api/v1.10.0", plumbing.ZeroHash),
+		plumbing.NewHashReference("refs/heads/release/web/v1.2.0", plumbing.ZeroHash),
+	}, nil)
+
+	branches, err := mockRemoteBranch.GetRemoteBranches("https://github.com/fhopfensperger/amqp-sb-client.git", "release", true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"refs/heads/release/api/v1.10.0", "refs/heads/release/web/v1.3.0"}, branches)
+}
@@ -0,0 +1,254 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Constraint is a semver range like ">=2.0.0 <2.1.0". Comparators separated by spaces must all match, alternatives are
+// separated by ||. Supported are =, !=, >, >=, <, <=, ~ (same minor), ^ (same major) and wildcards like 1.2.x or 1.2.
+type Constraint struct {
+	raw          string
+	alternatives [][]comparator
+}
+
+// comparator compares a version with the version of the comparator
+type comparator struct {
+	op      string
+	version ParsedVersion
+}
+
+var operators = []string{">=", "<=", "!=", ">", "<", "=", "~", "^"}
+
+// ParseConstraint parses a semver range, see Constraint
+func ParseConstraint(s string) (Constraint, error) {
+	c := Constraint{raw: s}
+	for _, alternative := range strings.Split(s, "||") {
+		// Join operators separated from their version by a space, e.g. ">= 2.0.0"
+		var fields []string
+		for _, field := range strings.Fields(alternative) {
+			if n := len(fields); n > 0 && isOperator(fields[n-1]) {
+				fields[n-1] += field
+			} else {
+				fields = append(fields, field)
+			}
+		}
+		if len(fields) == 0 {
+			return Constraint{}, fmt.Errorf("invalid constraint %q: empty range", s)
+		}
+		var comparators []comparator
+		for _, field := range fields {
+			cs, err := parseComparator(field)
+			if err != nil {
+				return Constraint{}, fmt.Errorf("invalid constraint %q: %w", s, err)
+			}
+			comparators = append(comparators, cs...)
+		}
+		// Like the upper bound of a partial version, <2.1.0 does not contain the pre-releases of v2.1.0, unless the
+		// range has a pre-release of v2.1.0, e.g. >=2.1.0-rc.1 <2.1.0
+		for i, cmp := range comparators {
+			if cmp.op == "<" && !cmp.version.IsPrerelease() && !hasPrereleaseOf(comparators, cmp.version) {
+				comparators[i].version.Prerelease = "0"
+			}
+		}
+		c.alternatives = append(c.alternatives, comparators)
+	}
+	return c, nil
+}
+
+// Check reports if the version, e.g. v2.0.1 or 2.0.1, satisfies the constraint
+func (c Constraint) Check(version string) bool {
+	v, ok := SemVerParser{}.Parse(version)
+	if !ok || v.Original != version {
+		return false
+	}
+	return c.CheckVersion(v)
+}
+
+// CheckVersion reports if the parsed version satisfies the constraint
+func (c Constraint) CheckVersion(version ParsedVersion) bool {
+	for _, comparators := range c.alternatives {
+		if matchAll(comparators, version) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c Constraint) String() string {
+	return c.raw
+}
+
+func matchAll(comparators []comparator, version ParsedVersion) bool {
+	for _, c := range comparators {
+		cmp := version.Compare(c.version)
+		var ok bool
+		switch c.op {
+		case "=":
+			ok = cmp == 0
+		case "!=":
+			ok = cmp != 0
+		case ">":
+			ok = cmp > 0
+		case ">=":
+			ok = cmp >= 0
+		case "<":
+			ok = cmp < 0
+		case "<=":
+			ok = cmp <= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// hasPrereleaseOf reports if one of the comparators has a pre-release of the version
+func hasPrereleaseOf(comparators []comparator, version ParsedVersion) bool {
+	for _, c := range comparators {
+		if c.version.IsPrerelease() && c.version.compareParts(version) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func isOperator(s string) bool {
+	for _, op := range operators {
+		if s == op {
+			return true
+		}
+	}
+	return false
+}
+
+// parseComparator parses a comparator like >=1.2.3, ~1.2 or 1.x into the comparators of its range
+func parseComparator(s string) ([]comparator, error) {
+	op := ""
+	for _, o := range operators {
+		if strings.HasPrefix(s, o) {
+			op = o
+			break
+		}
+	}
+	version, _, _ := strings.Cut(strings.TrimPrefix(strings.TrimPrefix(s, op), "v"), "+")
+	version, prerelease, _ := strings.Cut(version, "-")
+	parts, err := parseParts(version)
+	if err != nil {
+		return nil, err
+	}
+	partial := len(parts) < 3
+	if prerelease != "" && partial {
+		return nil, fmt.Errorf("%s needs a complete version for the pre-release", s)
+	}
+	lower := ParsedVersion{Parts: parts, Prerelease: prerelease}
+	// upper is the lowest pre-release of the first version after the range of a partial version, e.g. v1.3.0-0 for
+	// 1.2, so the range does not contain the pre-releases of v1.3.0
+	upper := func(n int) ParsedVersion {
+		bumped := append([]int{}, parts[:n]...)
+		bumped[n-1]++
+		return ParsedVersion{Parts: bumped, Prerelease: "0"}
+	}
+
+	switch op {
+	case "", "=":
+		if !partial {
+			return []comparator{{"=", lower}}, nil
+		}
+		if len(parts) == 0 {
+			return nil, nil
+		}
+		return []comparator{{">=", lower}, {"<", upper(len(parts))}}, nil
+	case "!=":
+		if partial {
+			return nil, fmt.Errorf("%s needs a complete version", s)
+		}
+		return []comparator{{"!=", lower}}, nil
+	case ">", "<=":
+		if len(parts) == 0 {
+			return nil, fmt.Errorf("%s needs a version", s)
+		}
+		if !partial {
+			return []comparator{{op, lower}}, nil
+		}
+		if op == ">" {
+			return []comparator{{">=", upper(len(parts))}}, nil
+		}
+		return []comparator{{"<", upper(len(parts))}}, nil
+	case ">=", "<":
+		if len(parts) == 0 {
+			return nil, fmt.Errorf("%s needs a version", s)
+		}
+		return []comparator{{op, lower}}, nil
+	case "~":
+		if len(parts) == 0 {
+			return nil, fmt.Errorf("%s needs a version", s)
+		}
+		n := len(parts)
+		if n > 2 {
+			n = 2
+		}
+		return []comparator{{">=", lower}, {"<", upper(n)}}, nil
+	case "^":
+		if len(parts) == 0 {
+			return nil, fmt.Errorf("%s needs a version", s)
+		}
+		// The first non-zero part must not change, e.g. ^0.2.3 allows 0.2.x
+		n := 1
+		for n < len(parts) && parts[n-1] == 0 {
+			n++
+		}
+		return []comparator{{">=", lower}, {"<", upper(n)}}, nil
+	}
+	return nil, fmt.Errorf("unknown operator in %s", s)
+}
+
+// parseParts parses the numeric parts of a version, a wildcard x, X or * ends the version, e.g. 1.2.x is [1 2]
+func parseParts(s string) ([]int, error) {
+	var parts []int
+	for _, p := range strings.Split(s, ".") {
+		if p == "x" || p == "X" || p == "*" {
+			break
+		}
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid version %q", s)
+		}
+		parts = append(parts, n)
+	}
+	if len(parts) > 4 {
+		return nil, fmt.Errorf("invalid version %q", s)
+	}
+	return parts, nil
+}
+
+// SplitByConstraint splits the branches into the ones whose version satisfies the constraint and the others, branches
+// without a version never satisfy it. The order of the branches is kept.
+func SplitByConstraint(branches []string, c Constraint, parser VersionParser) (matching []string, others []string) {
+	for _, b := range branches {
+		if version, ok := versionParserOr(parser).Parse(b); ok && c.CheckVersion(version) {
+			matching = append(matching, b)
+		} else {
+			others = append(others, b)
+		}
+	}
+	return matching, others
+}
@@ -0,0 +1,89 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstraint_Check(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{">=2.0.0 <2.1.0", "v2.0.0", true},
+		{">=2.0.0 <2.1.0", "v2.0.9", true},
+		{">=2.0.0 <2.1.0", "v2.1.0", false},
+		{">= 2.0.0 < 2.1.0", "v1.9.9", false},
+		{"<1.0.0 || >=3.0.0", "v0.9.0", true},
+		{"<1.0.0 || >=3.0.0", "v2.0.0", false},
+		{"<1.0.0 || >=3.0.0", "v3.0.1", true},
+		{"=1.2.3", "1.2.3", true},
+		{"1.2.3", "v1.2.4", false},
+		{"!=1.2.3", "v1.2.4", true},
+		{">1.2.3", "v1.2.3", false},
+		{"<=1.2.3", "v1.2.3", true},
+		{"1.2.x", "v1.2.7", true},
+		{"1.2", "v1.3.0", false},
+		{"1.*", "v1.9.0", true},
+		{">1.2", "v1.2.9", false},
+		{">1.2", "v1.3.0", true},
+		{"<=1.2", "v1.2.9", true},
+		{"~1.2.3", "v1.2.9", true},
+		{"~1.2.3", "v1.3.0", false},
+		{"^1.2.3", "v1.9.0", true},
+		{"^1.2.3", "v2.0.0", false},
+		{"^0.2.3", "v0.3.0", false},
+		{"*", "v9.9.9", true},
+		{">=1.0.0", "master", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.constraint+" "+tt.version, func(t *testing.T) {
+			c, err := ParseConstraint(tt.constraint)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, c.Check(tt.version))
+		})
+	}
+}
+
+func TestParseConstraint_invalid(t *testing.T) {
+	for _, s := range []string{"", ">=", "1.0.0 ||", ">=a.b.c", "1.2.3.4.5", "!=1.2", "=>1.0.0"} {
+		_, err := ParseConstraint(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestSplitByConstraint(t *testing.T) {
+	c, _ := ParseConstraint(">=3.0.0")
+	matching, others := SplitByConstraint([]string{
+		"refs/heads/release/v2.9.0", "refs/heads/release/v3.0.0", "refs/heads/master", "refs/heads/release/v3.1.0",
+	}, c, nil)
+	assert.Equal(t, []string{"refs/heads/release/v3.0.0", "refs/heads/release/v3.1.0"}, matching)
+	assert.Equal(t, []string{"refs/heads/release/v2.9.0", "refs/heads/master"}, others)
+}
+
+func TestConstraint_Check_prerelease(t *testing.T) {
+	c, err := ParseConstraint(">=2.0.0-rc.1 <2.0.0")
+	assert.NoError(t, err)
+	assert.True(t, c.Check("v2.0.0-rc.2"))
+	assert.False(t, c.Check("v2.0.0-beta"))
+	assert.False(t, c.Check("v2.0.0"))
+
+	c, _ = ParseConstraint("~2.0")
+	assert.True(t, c.Check("v2.0.5-rc.1"))
+	assert.False(t, c.Check("v2.1.0-rc.1"))
+
+	// An explicit upper bound excludes the pre-releases of its version like the partial forms
+	for _, raw := range []string{">=2.0.0 <2.1.0", "<2.1", "2.0.x"} {
+		c, _ = ParseConstraint(raw)
+		assert.True(t, c.Check("v2.0.1-rc.1"), raw)
+		assert.False(t, c.Check("v2.1.0-rc.1"), raw)
+		assert.False(t, c.Check("v2.1.0"), raw)
+	}
+	c, _ = ParseConstraint("<=2.1.0")
+	assert.True(t, c.Check("v2.1.0-rc.1"))
+
+	_, err = ParseConstraint(">=2.0-rc.1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+// Errors returned by RemoteBranch, they can be checked with errors.Is
+var (
+	// ErrNoBranchFilter is returned by GetRemoteBranches if no branch filter is defined
+	ErrNoBranchFilter = errors.New("no branch filter defined")
+	// ErrAuthentication is returned if the remote rejected the credentials
+	ErrAuthentication = errors.New("authentication failed")
+	// ErrRepositoryNotFound is returned if the remote repository does not exist
+	ErrRepositoryNotFound = errors.New("repository not found")
+	// ErrEmptyRepository is returned if the remote repository has no references
+	ErrEmptyRepository = errors.New("remote repository is empty")
+	// ErrNoDefaultBranch is returned by MergedBranches if the default branch of the remote could not be resolved
+	ErrNoDefaultBranch = errors.New("could not resolve the default branch")
+	// ErrPushRejected is returned by CleanBranches if the remote rejected the deletion
+	ErrPushRejected = errors.New("push rejected")
+)
+
+// remoteError is an error of the remote classified by one of the errors of this package
+type remoteError struct {
+	kind error
+	err  error
+}
+
+func (e *remoteError) Error() string {
+	if strings.Contains(e.err.Error(), e.kind.Error()) {
+		return e.err.Error()
+	}
+	return e.kind.Error() + ": " + e.err.Error()
+}
+
+func (e *remoteError) Unwrap() []error {
+	return []error{e.kind, e.err}
+}
+
+// wrapError wraps an error of the remote of the repo with the matching error of this package
+func wrapError(repoURL string, err error) error {
+	switch {
+	case errors.Is(err, transport.ErrAuthenticationRequired), errors.Is(err, transport.ErrAuthorizationFailed):
+		err = &remoteError{kind: ErrAuthentication, err: err}
+	case errors.Is(err, transport.ErrRepositoryNotFound):
+		err = &remoteError{kind: ErrRepositoryNotFound, err: err}
+	case errors.Is(err, transport.ErrEmptyRemoteRepository):
+		err = &remoteError{kind: ErrEmptyRepository, err: err}
+	}
+	return fmt.Errorf("%s: %w", repoURL, err)
+}
+
+// wrapPushError wraps an error of a push, errors not caused by the transport mean the remote rejected the push
+func wrapPushError(repoURL string, err error) error {
+	wrapped := wrapError(repoURL, err)
+	var classified *remoteError
+	if errors.As(wrapped, &classified) {
+		return wrapped
+	}
+	return fmt.Errorf("%s: %w", repoURL, &remoteError{kind: ErrPushRejected, err: err})
+}
+
+// wrapError wraps an error of the remote initialized by GetRemoteBranches
+func (m *RemoteBranch) wrapError(err error) error {
+	return wrapError(m.gitClient.Config().URLs[0], err)
+}
@@ -0,0 +1,120 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Exclusion protects branches from deletion. A rule without a prefix matches a branch exactly by its full name
+// (refs/heads/release/v1.0.0), its short name (release/v1.0.0) or its version (v1.0.0 or 1.0.0). Rules with the prefix
+// glob:, regex: or semver: match the short name by a glob, the whole short name by a regex or the version by a semver
+// range, see Constraint.
+type Exclusion struct {
+	rule       string
+	kind       string
+	pattern    string
+	regex      *regexp.Regexp
+	constraint Constraint
+}
+
+// Kinds of exclusions
+const (
+	exclusionExact  = "exact"
+	exclusionGlob   = "glob"
+	exclusionRegex  = "regex"
+	exclusionSemver = "semver"
+)
+
+// ParseExclusion parses a rule of the exclusion list, see Exclusion
+func ParseExclusion(rule string) (Exclusion, error) {
+	e := Exclusion{rule: rule, kind: exclusionExact, pattern: rule}
+	if kind, pattern, ok := strings.Cut(rule, ":"); ok {
+		switch kind {
+		case exclusionGlob, exclusionRegex, exclusionSemver:
+			e.kind, e.pattern = kind, pattern
+		}
+	}
+	if e.pattern == "" {
+		return Exclusion{}, fmt.Errorf("invalid exclusion %q: empty pattern", rule)
+	}
+
+	var err error
+	switch e.kind {
+	case exclusionGlob:
+		_, err = path.Match(e.pattern, "")
+	case exclusionRegex:
+		e.regex, err = regexp.Compile("^(?:" + e.pattern + ")$")
+	case exclusionSemver:
+		e.constraint, err = ParseConstraint(e.pattern)
+	}
+	if err != nil {
+		return Exclusion{}, fmt.Errorf("invalid exclusion %q: %w", rule, err)
+	}
+	return e, nil
+}
+
+// ParseExclusions parses all rules of the exclusion list
+func ParseExclusions(rules []string) ([]Exclusion, error) {
+	exclusions := make([]Exclusion, 0, len(rules))
+	for _, rule := range rules {
+		e, err := ParseExclusion(rule)
+		if err != nil {
+			return nil, err
+		}
+		exclusions = append(exclusions, e)
+	}
+	return exclusions, nil
+}
+
+// Match reports if the branch, e.g. refs/heads/release/v1.0.0, matches the exclusion. The version of the branch is
+// parsed by the parser, the SemVerParser if it is nil.
+func (e Exclusion) Match(branch string, parser VersionParser) bool {
+	name := plumbing.ReferenceName(branch).Short()
+	version, versioned := versionParserOr(parser).Parse(branch)
+	switch e.kind {
+	case exclusionGlob:
+		ok, _ := path.Match(e.pattern, name)
+		return ok
+	case exclusionRegex:
+		return e.regex.MatchString(name)
+	case exclusionSemver:
+		return versioned && e.constraint.CheckVersion(version)
+	}
+	return e.pattern == branch || e.pattern == name ||
+		(versioned && strings.TrimPrefix(e.pattern, "v") == strings.TrimPrefix(version.Original, "v"))
+}
+
+// String returns the rule as it was parsed
+func (e Exclusion) String() string {
+	return e.rule
+}
+
+// matchingExclusion returns the first exclusion the branch matches
+func matchingExclusion(exclusions []Exclusion, branch string, parser VersionParser) (Exclusion, bool) {
+	for _, e := range exclusions {
+		if e.Match(branch, parser) {
+			return e, true
+		}
+	}
+	return Exclusion{}, false
+}
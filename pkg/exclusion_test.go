@@ -0,0 +1,71 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExclusion_Match(t *testing.T) {
+	tests := []struct {
+		rule   string
+		branch string
+		want   bool
+	}{
+		{"v1.1.2", "/head/release/v1.1.2", true},
+		{"v.1.1.9", "head/release/v1.1.8", false},
+		{"v1.1.2", "refs/heads/release/v1.1.2", true},
+		{"1.1.2", "refs/heads/release/v1.1.2", true},
+		{"v1.1", "refs/heads/release/v1.1.2", false},
+		{"release/v1.1.2", "refs/heads/release/v1.1.2", true},
+		{"release/v1", "refs/heads/release/v1.1.2", false},
+		{"refs/heads/release/v1.1.2", "refs/heads/release/v1.1.2", true},
+		{"glob:release/v1.*", "refs/heads/release/v1.1.2", true},
+		{"glob:release/v1.*", "refs/heads/release/v10.0.0", false},
+		{"regex:release/v1\\.1\\.\\d+", "refs/heads/release/v1.1.2", true},
+		{"regex:v1\\.1\\.\\d+", "refs/heads/release/v1.1.2", false},
+		{"semver:>=2.0.0 <2.1.0", "refs/heads/release/v2.0.5", true},
+		{"semver:>=2.0.0 <2.1.0", "refs/heads/release/v2.1.0", false},
+		{"semver:1.x", "refs/heads/master", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.rule+" "+tt.branch, func(t *testing.T) {
+			e, err := ParseExclusion(tt.rule)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, e.Match(tt.branch, nil))
+		})
+	}
+}
+
+func TestExclusion_Match_calver(t *testing.T) {
+	branch := "refs/heads/release/1.0/2024.03"
+	for rule, want := range map[string]bool{"2024.03": true, "1.0": false, "semver:>=2024.1": true, "semver:1.x": false} {
+		e, err := ParseExclusion(rule)
+		assert.NoError(t, err)
+		assert.Equal(t, want, e.Match(branch, CalVerParser{}), rule)
+	}
+	e, _ := ParseExclusion("1.0")
+	assert.True(t, e.Match(branch, nil))
+}
+
+func TestParseExclusions_invalid(t *testing.T) {
+	for _, rule := range []string{"", "glob:release/[v", "regex:release/(v", "semver:>=2.0.0 <", "semver:"} {
+		_, err := ParseExclusions([]string{"v1.0.0", rule})
+		assert.Error(t, err, rule)
+	}
+}
+
+func TestExcludeBranches_rules(t *testing.T) {
+	branches, excluded, err := ExcludeBranches([]string{
+		"refs/heads/release/v1.9.0", "refs/heads/release/v2.0.1", "refs/heads/release/v2.1.0",
+	}, []string{"semver:~2.0", "glob:release/v1.*"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"refs/heads/release/v2.1.0"}, branches)
+	assert.Equal(t, map[string]string{
+		"refs/heads/release/v1.9.0": "glob:release/v1.*",
+		"refs/heads/release/v2.0.1": "semver:~2.0",
+	}, excluded)
+
+	_, _, err = ExcludeBranches([]string{"refs/heads/release/v2.1.0"}, []string{"regex:("}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,89 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Filter selects branches by their short name, e.g. release/v1.0.0 for refs/heads/release/v1.0.0
+type Filter struct {
+	kind    string
+	pattern string
+	regex   *regexp.Regexp
+}
+
+// Kinds of filters
+const (
+	filterSubstring = "substring"
+	filterRegex     = "regex"
+	filterGlob      = "glob"
+)
+
+// SubstringFilter matches branches which contain the substring
+func SubstringFilter(substring string) Filter {
+	return Filter{kind: filterSubstring, pattern: substring}
+}
+
+// RegexFilter matches branches which contain a match of the regular expression, use ^ and $ to match the whole name
+func RegexFilter(expr string) (Filter, error) {
+	regex, err := regexp.Compile(expr)
+	if err != nil {
+		return Filter{}, fmt.Errorf("invalid regex filter %q: %w", expr, err)
+	}
+	return Filter{kind: filterRegex, pattern: expr, regex: regex}, nil
+}
+
+// GlobFilter matches branches whose whole name matches the glob pattern, e.g. release/v*. A * does not match a /.
+func GlobFilter(pattern string) (Filter, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return Filter{}, fmt.Errorf("invalid glob filter %q: %w", pattern, err)
+	}
+	return Filter{kind: filterGlob, pattern: pattern}, nil
+}
+
+// Match reports if the short name of the branch matches the filter
+func (f Filter) Match(name string) bool {
+	switch f.kind {
+	case filterRegex:
+		return f.regex.MatchString(name)
+	case filterGlob:
+		ok, _ := path.Match(f.pattern, name)
+		return ok
+	}
+	return strings.Contains(name, f.pattern)
+}
+
+func (f Filter) String() string {
+	if f.kind == filterSubstring {
+		return f.pattern
+	}
+	return f.kind + ":" + f.pattern
+}
+
+// matchAny reports if the short name of the branch matches one of the filters
+func matchAny(filters []Filter, name string) bool {
+	for _, f := range filters {
+		if f.Match(name) {
+			return true
+		}
+	}
+	return false
+}
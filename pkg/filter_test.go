@@ -0,0 +1,76 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilter_Match(t *testing.T) {
+	regex, _ := RegexFilter(`^release/v\d+\.\d+\.\d+$`)
+	glob, _ := GlobFilter("release/v*")
+	tests := []struct {
+		filter Filter
+		name   string
+		want   bool
+	}{
+		{SubstringFilter("release"), "release/v1.0.0", true},
+		{SubstringFilter("release"), "prerelease-experiments", true},
+		{regex, "release/v1.0.0", true},
+		{regex, "release/v1.0.0-rc1", false},
+		{regex, "feature/release/v1.0.0", false},
+		{glob, "release/v1.0.0", true},
+		{glob, "release/v1/hotfix", false},
+		{glob, "feature/release-notes", false},
+		{glob, "prerelease-experiments", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter.String()+" "+tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.Match(tt.name))
+		})
+	}
+}
+
+func TestFilter_invalid(t *testing.T) {
+	_, err := RegexFilter("release/(v")
+	assert.Error(t, err)
+	_, err = GlobFilter("release/[v")
+	assert.Error(t, err)
+}
+
+func TestFilter_String(t *testing.T) {
+	regex, _ := RegexFilter("^release/")
+	glob, _ := GlobFilter("release/v*")
+	assert.Equal(t, "release", SubstringFilter("release").String())
+	assert.Equal(t, "regex:^release/", regex.String())
+	assert.Equal(t, "glob:release/v*", glob.String())
+}
+
+func TestGetRemoteBranchesMatching(t *testing.T) {
+	remote := new(remoteBranchMock)
+	mockRemoteBranch := New(remote, nil)
+	remote.On("List", &git.ListOptions{}).Return([]*plumbing.Reference{
+		plumbing.NewHashReference("refs/heads/release/v1.0.0", plumbing.ZeroHash),
+		plumbing.NewHashReference("refs/heads/prerelease-experiments", plumbing.ZeroHash),
+		plumbing.NewHashReference("refs/heads/feature/release-notes", plumbing.ZeroHash),
+		plumbing.NewHashReference("refs/heads/hotfix/v1.0.1", plumbing.ZeroHash),
+	}, nil)
+	glob, _ := GlobFilter("release/v*")
+	regex, _ := RegexFilter("^hotfix/")
+
+	branches, err := mockRemoteBranch.GetRemoteBranchesMatching("https://github.com/fhopfensperger/amqp-sb-client.git", []Filter{glob, regex}, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"refs/heads/release/v1.0.0", "refs/heads/hotfix/v1.0.1"}, branches)
+}
+
+func TestGetRemoteBranchesMatching_no_filter(t *testing.T) {
+	remote := new(remoteBranchMock)
+	mockRemoteBranch := New(remote, nil)
+
+	_, err := mockRemoteBranch.GetRemoteBranchesMatching("https://github.com/fhopfensperger/amqp-sb-client.git", nil, false)
+
+	assert.ErrorIs(t, err, ErrNoBranchFilter)
+}
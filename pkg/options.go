@@ -0,0 +1,76 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"regexp"
+
+	"github.com/rs/zerolog"
+)
+
+// Option configures a RemoteBranch created by New
+type Option func(*RemoteBranch)
+
+// WithArchiveTags archives every branch deleted by CleanBranches as a tag named prefix + short branch name,
+// e.g. the prefix archive/ archives refs/heads/release/v1.1.0 as refs/tags/archive/release/v1.1.0
+func WithArchiveTags(prefix string) Option {
+	return func(m *RemoteBranch) {
+		m.archiveTagPrefix = prefix
+	}
+}
+
+// WithBackup writes a git bundle of the branches into the directory before CleanBranches deletes them
+func WithBackup(dir string) Option {
+	return func(m *RemoteBranch) {
+		m.backupDir = dir
+	}
+}
+
+// WithLogger writes the logs of the RemoteBranch to the logger instead of the global logger, e.g. to group the logs of
+// repos processed concurrently
+func WithLogger(logger zerolog.Logger) Option {
+	return func(m *RemoteBranch) {
+		m.logger = logger
+	}
+}
+
+// WithVersionParser sets the parser of the branch versions, which sorts the branches and selects the latest one
+func WithVersionParser(parser VersionParser) Option {
+	return func(m *RemoteBranch) {
+		m.versions = parser
+	}
+}
+
+// WithGroupBy groups the branches by component, so the latest branch of every component is selected, see ParseGroupBy
+func WithGroupBy(groupBy *regexp.Regexp) Option {
+	return func(m *RemoteBranch) {
+		m.groupBy = groupBy
+	}
+}
+
+// DefaultArchiveTagPrefix is the default prefix of the archive tags of WithArchiveTags
+const DefaultArchiveTagPrefix = "archive/"
+
+// WithTags selects tags instead of branches, GetRemoteBranches then returns tags like refs/tags/v1.0.0, which are
+// deleted by CleanBranches the same way as branches. Tags under the archivePrefix, e.g. the archive/ of
+// WithArchiveTags, are never selected, so the archives of deleted branches are never deleted.
+func WithTags(archivePrefix string) Option {
+	return func(m *RemoteBranch) {
+		m.tags = true
+		m.archivedTags = archivePrefix
+	}
+}
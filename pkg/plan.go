@@ -0,0 +1,95 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// Plan is the exact set of branches to delete, written by the plan command and executed by the apply command
+type Plan struct {
+	Created time.Time  `json:"created"`
+	Repos   []RepoPlan `json:"repos"`
+}
+
+// RepoPlan contains the branches to delete from a repo
+type RepoPlan struct {
+	URL      string          `json:"url"`
+	Branches []PlannedBranch `json:"branches"`
+}
+
+// PlannedBranch is a branch to delete, it is only deleted if its tip is still the commit SHA
+type PlannedBranch struct {
+	Name string `json:"name"`
+	SHA  string `json:"sha"`
+}
+
+// PlanBranches returns the plan to delete the branches, which must have been found by GetRemoteBranches
+func (m *RemoteBranch) PlanBranches(repoURL string, branches []string) RepoPlan {
+	plan := RepoPlan{URL: repoURL, Branches: []PlannedBranch{}}
+	for _, b := range branches {
+		plan.Branches = append(plan.Branches, PlannedBranch{Name: b, SHA: m.hashes[b].String()})
+	}
+	return plan
+}
+
+// ApplyPlan deletes exactly the planned branches, branches whose tip is not the planned SHA anymore are skipped
+func (m *RemoteBranch) ApplyPlan(plan RepoPlan, dryRun bool) ([]string, error) {
+	m.initClient(plan.URL)
+
+	var branches []string
+	for _, b := range plan.Branches {
+		m.hashes[b.Name] = plumbing.NewHash(b.SHA)
+		branches = append(branches, b.Name)
+	}
+	return m.CleanBranches(branches, nil, dryRun)
+}
+
+// WritePlan writes the plan as JSON file
+func WritePlan(file string, plan Plan) error {
+	content, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(content, '\n'), 0o644)
+}
+
+// ReadPlan reads a plan written by WritePlan
+func ReadPlan(file string) (Plan, error) {
+	var plan Plan
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return plan, err
+	}
+	if err := json.Unmarshal(content, &plan); err != nil {
+		return plan, fmt.Errorf("invalid plan %s: %w", file, err)
+	}
+	for _, repo := range plan.Repos {
+		for _, b := range repo.Branches {
+			name := plumbing.ReferenceName(b.Name)
+			if !(name.IsBranch() || name.IsTag()) || !plumbing.IsHash(b.SHA) {
+				return plan, fmt.Errorf("invalid branch or tag %s with SHA %q in plan %s", b.Name, b.SHA, file)
+			}
+		}
+	}
+	return plan, nil
+}
@@ -0,0 +1,100 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRemoteBranch_PlanBranches(t *testing.T) {
+	remote := new(remoteBranchMock)
+	mockRemoteBranch := New(remote, nil)
+	hash := plumbing.NewHash("1111111111111111111111111111111111111111")
+	remote.On("List", &git.ListOptions{}).Return([]*plumbing.Reference{
+		plumbing.NewHashReference("refs/heads/release/v1.0.0", hash),
+		plumbing.NewHashReference("refs/heads/release/v1.0.1", hash),
+	}, nil)
+
+	_, err := mockRemoteBranch.GetRemoteBranches("https://github.com/fhopfensperger/amqp-sb-client.git", "release", false)
+	assert.NoError(t, err)
+	plan := mockRemoteBranch.PlanBranches("https://github.com/fhopfensperger/amqp-sb-client.git", []string{"refs/heads/release/v1.0.0"})
+
+	assert.Equal(t, RepoPlan{
+		URL:      "https://github.com/fhopfensperger/amqp-sb-client.git",
+		Branches: []PlannedBranch{{Name: "refs/heads/release/v1.0.0", SHA: hash.String()}},
+	}, plan)
+}
+
+func TestWritePlan_ReadPlan(t *testing.T) {
+	planFile := filepath.Join(t.TempDir(), "plan.json")
+	plan := Plan{
+		Created: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		Repos: []RepoPlan{{
+			URL: "https://github.com/fhopfensperger/amqp-sb-client.git",
+			Branches: []PlannedBranch{
+				{Name: "refs/heads/release/v1.0.0", SHA: "1111111111111111111111111111111111111111"},
+				{Name: "refs/tags/v1.0.0-rc.1", SHA: "2222222222222222222222222222222222222222"},
+			},
+		}},
+	}
+
+	assert.NoError(t, WritePlan(planFile, plan))
+	readPlan, err := ReadPlan(planFile)
+	assert.NoError(t, err)
+	assert.Equal(t, plan, readPlan)
+}
+
+func TestReadPlan_invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"no-json", "no json"},
+		{"invalid-sha", `{"repos":[{"url":"https://github.com/fhopfensperger/amqp-sb-client.git","branches":[{"name":"refs/heads/release/v1.0.0","sha":"123"}]}]}`},
+		{"no-branch", `{"repos":[{"url":"https://github.com/fhopfensperger/amqp-sb-client.git","branches":[{"name":"release/v1.0.0","sha":"1111111111111111111111111111111111111111"}]}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			planFile := filepath.Join(t.TempDir(), "plan.json")
+			_ = os.WriteFile(planFile, []byte(tt.content), 0o644)
+			_, err := ReadPlan(planFile)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestReadPlan_not_found(t *testing.T) {
+	_, err := ReadPlan(filepath.Join(t.TempDir(), "plan.json"))
+	assert.Error(t, err)
+}
+
+func TestRemoteBranch_ApplyPlan(t *testing.T) {
+	remote := new(remoteBranchMock)
+	mockRemoteBranch := New(remote, nil)
+	remote.On("List", &git.ListOptions{}).Return([]*plumbing.Reference{
+		plumbing.NewHashReference("refs/heads/release/v1.0.0", plumbing.NewHash("1111111111111111111111111111111111111111")),
+		plumbing.NewHashReference("refs/heads/release/v1.0.1", plumbing.NewHash("3333333333333333333333333333333333333333")),
+	}, nil)
+	remote.On("Config").Return(&config.RemoteConfig{
+		Name: "amqp-sb-client.git",
+		URLs: []string{"https://github.com/fhopfensperger/amqp-sb-client.git"},
+	})
+
+	deletedBranches, err := mockRemoteBranch.ApplyPlan(RepoPlan{
+		URL: "https://github.com/fhopfensperger/amqp-sb-client.git",
+		Branches: []PlannedBranch{
+			{Name: "refs/heads/release/v1.0.0", SHA: "1111111111111111111111111111111111111111"},
+			{Name: "refs/heads/release/v1.0.1", SHA: "2222222222222222222222222222222222222222"},
+		},
+	}, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"refs/heads/release/v1.0.0"}, deletedBranches)
+	assert.Equal(t, []config.RefSpec{"1111111111111111111111111111111111111111:refs/heads/release/v1.0.0"}, remote.pushOptions.RequireRemoteRefs)
+}
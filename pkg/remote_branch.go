@@ -17,17 +17,17 @@ limitations under the License.
 package pkg
 
 import (
-	"os"
+	"fmt"
 	"regexp"
-	"sort"
 	"strings"
+	"time"
 
 	"github.com/go-git/go-git/v5/plumbing/transport"
 
-	"golang.org/x/mod/semver"
-
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
 
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
 	"github.com/go-git/go-git/v5/config"
@@ -43,67 +43,268 @@ type GitInterface interface {
 	List(*git.ListOptions) ([]*plumbing.Reference, error)
 	Config() *config.RemoteConfig
 	Push(*git.PushOptions) error
+	Fetch(*git.FetchOptions) error
 }
 
 //RemoteBranch to implement the interface
 type RemoteBranch struct {
 	gitClient GitInterface
 	auth      transport.AuthMethod
+	// storage holds the objects fetched from the remote
+	storage *memory.Storage
+	// hashes of the references found by GetRemoteBranches
+	hashes map[string]plumbing.Hash
+	// defaultBranch of the remote, resolved from HEAD by GetRemoteBranches
+	defaultBranch string
+	// archiveTagPrefix enables archiving deleted branches as tags, if not empty
+	archiveTagPrefix string
+	// backupDir enables a bundle backup of deleted branches, if not empty
+	backupDir string
+	// logger of the RemoteBranch, the global logger if not set by WithLogger
+	logger zerolog.Logger
+	// versions sorts the branches, the SemVerParser if not set by WithVersionParser
+	versions VersionParser
+	// groupBy selects the latest branch per component, set by WithGroupBy
+	groupBy *regexp.Regexp
+	// tags selects tags instead of branches, set by WithTags
+	tags bool
+	// archivedTags is the prefix of the archive tags, which are never selected as tags, set by WithTags
+	archivedTags string
 }
 
 //New constructor
-func New(client GitInterface, auth transport.AuthMethod) RemoteBranch {
-	return RemoteBranch{client, auth}
+func New(client GitInterface, auth transport.AuthMethod, opts ...Option) RemoteBranch {
+	m := RemoteBranch{gitClient: client, auth: auth, storage: memory.NewStorage(), hashes: map[string]plumbing.Hash{}, logger: log.Logger}
+	for _, opt := range opts {
+		opt(&m)
+	}
+	return m
 }
 
-var versionRegex = regexp.MustCompile(`v\d+(\.\d+)+`)
+// unshallowDepth fetches the complete history, even if commits were fetched shallow before (same as git fetch --unshallow)
+const unshallowDepth = 2147483647
 
 //GetRemoteBranches get remote branches from GitHub using the repoURL and the branchFilter
-func (m *RemoteBranch) GetRemoteBranches(repoURL string, branchFilter string, latest bool) []string {
+func (m *RemoteBranch) GetRemoteBranches(repoURL string, branchFilter string, latest bool) ([]string, error) {
 	if branchFilter == "" {
-		log.Warn().Msg("No branchfilter defined")
-		os.Exit(1)
+		return nil, ErrNoBranchFilter
 	}
-	if m.gitClient == nil {
-		m.gitClient = git.NewRemote(memory.NewStorage(), &config.RemoteConfig{
-			Name: "origin",
-			URLs: []string{repoURL},
-		})
+	return m.GetRemoteBranchesMatching(repoURL, []Filter{SubstringFilter(branchFilter)}, latest)
+}
+
+//GetRemoteBranchesMatching get remote branches using the repoURL, whose short name matches one of the filters
+func (m *RemoteBranch) GetRemoteBranchesMatching(repoURL string, filters []Filter, latest bool) ([]string, error) {
+	if len(filters) == 0 {
+		return nil, ErrNoBranchFilter
 	}
+	m.initClient(repoURL)
 
 	// We can then use every Remote functions to retrieve wanted information
 	refs, err := m.gitClient.List(&git.ListOptions{Auth: m.auth})
 	if err != nil {
-		log.Err(err).Msg("")
+		return nil, wrapError(repoURL, err)
 	}
 
+	m.defaultBranch = defaultBranch(refs)
+
 	// Filters the references list and only branches which apply to the filter
 	var branches []string
 	for _, ref := range refs {
-		if ref.Name().IsBranch() && strings.Contains(ref.Name().Short(), branchFilter) {
+		if ref.Name().String() == m.defaultBranch {
+			m.hashes[m.defaultBranch] = ref.Hash()
+		}
+		if m.selects(ref.Name()) && matchAny(filters, ref.Name().Short()) {
 			branches = append(branches, ref.Name().String())
+			m.hashes[ref.Name().String()] = ref.Hash()
 		}
 	}
-	sortBySemVer(branches)
-	if latest {
-		log.Info().Msgf("Latest branch: %v for repo %s and filter %s", branches[len(branches)-1], repoURL, branchFilter)
-		return []string{branches[len(branches)-1]}
+	sortByVersion(branches, m.versions)
+	if latest && len(branches) > 0 {
+		latestBranches := LatestPerComponent(branches, m.groupBy)
+		m.logger.Info().Msgf("Latest branch: %v for repo %s and filter %v", strings.Join(latestBranches, ", "), repoURL, filters)
+		return latestBranches, nil
 	}
-	log.Info().Msgf("Remote branches found: %v for repo %s and filter %s", branches, repoURL, branchFilter)
-	return branches
+	m.logger.Info().Msgf("Remote branches found: %v for repo %s and filter %v", branches, repoURL, filters)
+	return branches, nil
 }
 
-//FilterBranches which should be deleted, for the the moment there is semver.MajorMinor used
-//e.g. we have the following branches /release/v1.0.0 /release/v1.1.0 /release/v1.1.1 the function would
-//filter out /release/v1.1.0, as /release/v1.1.1 is newer than v1.1.0.
-func FilterBranches(branches []string) []string {
-	sortBySemVer(branches)
-	filteredBranches := branches[:0]
+// selects reports if the reference is a branch, or a tag which is no archive tag if tags are selected by WithTags
+func (m *RemoteBranch) selects(name plumbing.ReferenceName) bool {
+	if m.tags {
+		return name.IsTag() && (m.archivedTags == "" || !strings.HasPrefix(name.Short(), m.archivedTags))
+	}
+	return name.IsBranch()
+}
 
-	// TODO make it configuable
-	for i, b := range branches {
-		if i > 0 && semver.MajorMinor(versionRegex.FindString(b)) == semver.MajorMinor(versionRegex.FindString(branches[i-1])) {
-			filteredBranches = append(filteredBranches, branches[i-1])
+// initClient creates the git client for the repoURL, if no client was passed to New
+func (m *RemoteBranch) initClient(repoURL string) {
+	if m.gitClient == nil {
+		m.gitClient = git.NewRemote(m.storage, &config.RemoteConfig{
+			Name: "origin",
+			URLs: []string{repoURL},
+		})
+	}
+}
+
+//CommitDates fetches only the tip commits of the branches found by GetRemoteBranches and returns the date of the
+//last commit for every branch. Branches whose commit could not be fetched are missing in the result.
+func (m *RemoteBranch) CommitDates(branches []string) (map[string]time.Time, error) {
+	dates := map[string]time.Time{}
+	if len(branches) == 0 {
+		return dates, nil
+	}
+
+	if err := m.fetchTips(branches); err != nil {
+		return dates, m.wrapError(err)
+	}
+
+	for _, b := range branches {
+		commit, err := m.peeledCommit(m.hashes[b])
+		if err != nil {
+			m.logger.Err(err).Msgf("Could not get the last commit of branch %s", b)
+			continue
+		}
+		dates[b] = commit.Committer.When
+	}
+	return dates, nil
+}
+
+//MergedBranches checks for every branch if its tip commit is an ancestor of the default branch of the remote, it
+//returns the merged and the unmerged branches. The complete history of the default branch is fetched for this.
+func (m *RemoteBranch) MergedBranches(branches []string) (merged []string, unmerged []string, err error) {
+	if len(branches) == 0 {
+		return nil, nil, nil
+	}
+	if m.defaultBranch == "" {
+		return nil, branches, ErrNoDefaultBranch
+	}
+
+	err = m.gitClient.Fetch(&git.FetchOptions{
+		RefSpecs: []config.RefSpec{config.RefSpec("+" + m.defaultBranch + ":" + fetchedRef(m.defaultBranch))},
+		Depth:    unshallowDepth,
+		Tags:     git.NoTags,
+		Auth:     m.auth,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return nil, branches, m.wrapError(err)
+	}
+
+	// Annotated tags are peeled to their commits, their tag objects are fetched for this
+	commits := map[string]plumbing.Hash{}
+	if m.tags {
+		if err := m.fetchTips(branches); err != nil {
+			return nil, branches, m.wrapError(err)
+		}
+	}
+	tips := map[plumbing.Hash]bool{}
+	for _, b := range branches {
+		commits[b] = m.hashes[b]
+		if commit, err := m.peeledCommit(m.hashes[b]); err == nil {
+			commits[b] = commit.Hash
+		}
+		tips[commits[b]] = false
+	}
+	m.markReachable(m.hashes[m.defaultBranch], tips)
+
+	for _, b := range branches {
+		if tips[commits[b]] {
+			merged = append(merged, b)
+		} else {
+			m.logger.Info().Msgf("Refusing to delete branch %s as it is not merged into %s", b, m.defaultBranch)
+			unmerged = append(unmerged, b)
+		}
+	}
+	return merged, unmerged, nil
+}
+
+// markReachable walks the history starting at the given commit and marks every found commit of the tips map as
+// reachable. Missing commits, e.g. behind a shallow fetch boundary, are skipped.
+func (m *RemoteBranch) markReachable(start plumbing.Hash, tips map[plumbing.Hash]bool) {
+	found := 0
+	visited := map[plumbing.Hash]bool{}
+	pending := []plumbing.Hash{start}
+	for len(pending) > 0 && found < len(tips) {
+		hash := pending[0]
+		pending = pending[1:]
+		if visited[hash] {
+			continue
+		}
+		visited[hash] = true
+
+		if reached, ok := tips[hash]; ok && !reached {
+			tips[hash] = true
+			found++
+		}
+		commit, err := object.GetCommit(m.storage, hash)
+		if err != nil {
+			continue
+		}
+		pending = append(pending, commit.ParentHashes...)
+	}
+}
+
+// peeledCommit returns the commit of the hash, an annotated tag is peeled to the commit it points to
+func (m *RemoteBranch) peeledCommit(hash plumbing.Hash) (*object.Commit, error) {
+	if tag, err := object.GetTag(m.storage, hash); err == nil {
+		return tag.Commit()
+	}
+	return object.GetCommit(m.storage, hash)
+}
+
+// defaultBranch returns the branch HEAD points to, or an empty string if the remote does not advertise HEAD
+func defaultBranch(refs []*plumbing.Reference) string {
+	for _, ref := range refs {
+		if ref.Name() != plumbing.HEAD {
+			continue
+		}
+		if ref.Type() == plumbing.SymbolicReference {
+			return ref.Target().String()
+		}
+		// Without symref capability HEAD is a hash reference, try to find the branch with the same hash
+		for _, branch := range refs {
+			if branch.Name().IsBranch() && branch.Hash() == ref.Hash() {
+				return branch.Name().String()
+			}
+		}
+	}
+	return ""
+}
+
+// fetchTips fetches only the tip commits of the given references into the storage
+func (m *RemoteBranch) fetchTips(refs []string) error {
+	var refspecs []config.RefSpec
+	for _, r := range refs {
+		refspecs = append(refspecs, config.RefSpec("+"+r+":"+fetchedRef(r)))
+	}
+	err := m.gitClient.Fetch(&git.FetchOptions{
+		RefSpecs: refspecs,
+		Depth:    1,
+		Tags:     git.NoTags,
+		Auth:     m.auth,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return err
+	}
+	return nil
+}
+
+// fetchedRef returns the local reference name a remote reference is fetched into. Fetched references must not
+// be named like the remote ones, otherwise the prune push of CleanBranches would not delete them.
+func fetchedRef(name string) string {
+	return "refs/fetched/" + strings.TrimPrefix(name, "refs/")
+}
+
+//FilterBranches which should be deleted according to the retention policy
+//e.g. we have the following branches /release/v1.0.0 /release/v1.1.0 /release/v1.1.1 and the DefaultRetentionPolicy,
+//the function would filter out /release/v1.1.0, as /release/v1.1.1 is newer than v1.1.0.
+func FilterBranches(branches []string, policy RetentionPolicy) []string {
+	sortByVersion(branches, policy.Versions)
+	filteredBranches := make([]string, 0, len(branches))
+
+	kept := policy.kept(branches)
+	for _, b := range branches {
+		if !kept[b] {
+			filteredBranches = append(filteredBranches, b)
 		}
 	}
 	return filteredBranches
@@ -111,33 +312,39 @@ func FilterBranches(branches []string) []string {
 
 //CleanBranches deletes branches from the remote repo which are included in the branchesToDelete slice, it excludes
 //branches from the exclusionList. You can simulate the deletion, with dryRun
-func (m *RemoteBranch) CleanBranches(branchesToDelete []string, exclusionList []string, dryRun bool) (deletedBranches []string) {
+func (m *RemoteBranch) CleanBranches(branchesToDelete []string, exclusionList []string, dryRun bool) (deletedBranches []string, err error) {
 
 	repoURL := m.gitClient.Config().URLs[0]
 	if len(branchesToDelete) == 0 {
-		log.Info().Msgf("Nothing to delete for repo %s", repoURL)
-		return nil
+		m.logger.Info().Msgf("Nothing to delete for repo %s", repoURL)
+		return nil, nil
 	}
 
 	// Exclude branches from deletion
-	if len(exclusionList) != 0 {
-		tmp := branchesToDelete[:0]
-		for _, branch := range branchesToDelete {
-			if exclude, ok := contains(exclusionList, branch); !ok {
-				tmp = append(tmp, branch)
-			} else {
-				log.Info().Msgf("Excluding branch %s as it matches the exclusion list %s", branch, exclude)
-			}
-		}
-		branchesToDelete = tmp
+	branchesToDelete, excluded, err := ExcludeBranches(branchesToDelete, exclusionList, m.versions)
+	if err != nil {
+		return nil, err
+	}
+	for branch, exclude := range excluded {
+		m.logger.Info().Msgf("Excluding branch %s as it matches the exclusion list %s", branch, exclude)
+	}
+
+	if len(branchesToDelete) == 0 {
+		m.logger.Info().Msgf("Nothing to delete, all branches are excluded")
+		return nil, nil
 	}
 
+	// Only delete branches which were not changed since they have been listed
+	branchesToDelete, err = m.unchangedBranches(branchesToDelete)
+	if err != nil {
+		return nil, err
+	}
 	if len(branchesToDelete) == 0 {
-		log.Info().Msgf("Nothing to delete, all branches are excluded")
-		return nil
+		m.logger.Info().Msgf("Nothing to delete, all branches changed since listing")
+		return nil, nil
 	}
 
-	log.Info().Msgf("Going to delete branches: %v from repo %s", branchesToDelete, repoURL)
+	m.logger.Info().Msgf("Going to delete branches: %v from repo %s", branchesToDelete, repoURL)
 
 	// Clone repo temp
 	//r, err := git.Clone(memory.NewStorage(), nil, &git.CloneOptions{
@@ -152,50 +359,156 @@ func (m *RemoteBranch) CleanBranches(branchesToDelete []string, exclusionList []
 	//}
 
 	var refspecs []config.RefSpec
+	var leases []config.RefSpec
 	// Add branches to Delete into refspecs
 	for _, b := range branchesToDelete {
 		refspecs = append(refspecs, config.RefSpec(b+":"+b))
+		// The push is rejected if the branch tip differs from the listed one, like git push --force-with-lease
+		if hash, ok := m.hashes[b]; ok {
+			leases = append(leases, config.RefSpec(hash.String()+":"+b))
+		}
 	}
 
-	log.Info().Msg("Deleting...")
+	// Backup the branches before anything is deleted
+	if m.backupDir != "" && !dryRun {
+		if _, err := m.Backup(branchesToDelete, m.backupDir); err != nil {
+			return nil, fmt.Errorf("could not backup branches %v, nothing deleted: %w", branchesToDelete, err)
+		}
+	}
+
+	// Add the archive tags into the same refspecs, so they are created in the same push which deletes the branches
+	if m.archiveTagPrefix != "" {
+		tagRefspecs, err := m.archiveTags(branchesToDelete, dryRun)
+		if err != nil {
+			return nil, fmt.Errorf("could not archive branches %v as tags, nothing deleted: %w", branchesToDelete, err)
+		}
+		refspecs = append(refspecs, tagRefspecs...)
+	}
+
+	m.logger.Info().Msg("Deleting...")
 	// push to delete branches which are matches the refspecs
 	if !dryRun {
 		err := m.gitClient.Push(&git.PushOptions{
-			Prune:    true,
-			RefSpecs: refspecs,
-			Auth:     m.auth,
+			Prune:             true,
+			RefSpecs:          refspecs,
+			RequireRemoteRefs: leases,
+			Auth:              m.auth,
 		})
 		if err != nil {
-			log.Err(err).Msg("")
+			return nil, wrapPushError(repoURL, err)
 		}
-		log.Info().Msg("Branches deleted")
-		return branchesToDelete
+		m.logger.Info().Msg("Branches deleted")
+		return branchesToDelete, nil
 	}
-	log.Info().Msg("Dry run! Nothing deleted")
-	return nil
+	m.logger.Info().Msg("Dry run! Nothing deleted")
+	return nil, nil
 }
 
-func contains(s []string, e string) (string, bool) {
-	for _, a := range s {
-		if strings.Contains(e, a) {
-			return a, true
+//ExcludeBranches removes the branches from the slice which match the exclusionList, it returns the remaining branches
+//and the excluded branches with the rule of the exclusionList they matched. The rules are described at Exclusion, the
+//versions of the branches are parsed by the parser, the SemVerParser if it is nil.
+func ExcludeBranches(branches []string, exclusionList []string, parser VersionParser) ([]string, map[string]string, error) {
+	excluded := map[string]string{}
+	if len(exclusionList) == 0 {
+		return branches, excluded, nil
+	}
+	exclusions, err := ParseExclusions(exclusionList)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmp := branches[:0]
+	for _, branch := range branches {
+		if exclusion, ok := matchingExclusion(exclusions, branch, parser); !ok {
+			tmp = append(tmp, branch)
+		} else {
+			excluded[branch] = exclusion.String()
 		}
 	}
-	return "", false
+	return tmp, excluded, nil
 }
 
-func sortBySemVer(s []string) {
-	sort.SliceStable(s, func(i, j int) bool {
-		branchA := semver.Canonical(versionRegex.FindString(s[i]))
-		branchB := semver.Canonical(versionRegex.FindString(s[j]))
+//SHA returns the commit SHA of a branch found by GetRemoteBranches
+func (m *RemoteBranch) SHA(branch string) string {
+	if hash, ok := m.hashes[branch]; ok {
+		return hash.String()
+	}
+	return ""
+}
+
+//DefaultBranch returns the branch HEAD of the remote points to, e.g. refs/heads/main, resolved by GetRemoteBranches.
+//It is empty if the remote does not advertise HEAD.
+func (m *RemoteBranch) DefaultBranch() string {
+	return m.defaultBranch
+}
+
+//Version returns the version of a branch parsed by the SemVerParser, e.g. v1.1.0 for refs/heads/release/v1.1.0
+func Version(branch string) string {
+	return parseVersion(nil, branch).Original
+}
+
+// unchangedBranches lists the remote again and returns only the branches whose tip is still the one listed by
+// GetRemoteBranches. Branches which were not listed by GetRemoteBranches can't be checked and are returned as well.
+func (m *RemoteBranch) unchangedBranches(branches []string) ([]string, error) {
+	listed := false
+	for _, b := range branches {
+		if _, ok := m.hashes[b]; ok {
+			listed = true
+		}
+	}
+	if !listed {
+		return branches, nil
+	}
+
+	refs, err := m.gitClient.List(&git.ListOptions{Auth: m.auth})
+	if err != nil {
+		return nil, m.wrapError(err)
+	}
+	current := map[string]plumbing.Hash{}
+	for _, ref := range refs {
+		current[ref.Name().String()] = ref.Hash()
+	}
 
-		switch semver.Compare(branchA, branchB) {
-		case -1:
-			return true
-		case 0:
-			return false
+	unchanged := branches[:0]
+	for _, b := range branches {
+		listedHash, ok := m.hashes[b]
+		if !ok {
+			unchanged = append(unchanged, b)
+			continue
+		}
+		currentHash, exists := current[b]
+		switch {
+		case !exists:
+			m.logger.Info().Msgf("Skipping branch %s as it no longer exists", b)
+		case currentHash != listedHash:
+			m.logger.Warn().Msgf("Skipping branch %s as its tip moved from %s to %s since listing", b, listedHash, currentHash)
 		default:
-			return false
+			unchanged = append(unchanged, b)
 		}
-	})
+	}
+	return unchanged, nil
+}
+
+// archiveTags creates a local tag for every branch pointing at its tip commit and returns the refspecs to push them
+func (m *RemoteBranch) archiveTags(branches []string, dryRun bool) ([]config.RefSpec, error) {
+	if !dryRun {
+		if err := m.fetchTips(branches); err != nil {
+			return nil, err
+		}
+	}
+
+	var refspecs []config.RefSpec
+	for _, b := range branches {
+		if _, ok := m.hashes[b]; !ok {
+			return nil, fmt.Errorf("unknown tip commit of branch %s", b)
+		}
+		tag := plumbing.NewTagReferenceName(m.archiveTagPrefix + plumbing.ReferenceName(b).Short())
+		m.logger.Info().Msgf("Archiving branch %s as tag %s", b, tag)
+		if !dryRun {
+			if err := m.storage.SetReference(plumbing.NewHashReference(tag, m.hashes[b])); err != nil {
+				return nil, err
+			}
+		}
+		refspecs = append(refspecs, config.RefSpec(tag+":"+tag))
+	}
+	return refspecs, nil
 }
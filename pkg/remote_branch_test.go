@@ -1,52 +1,27 @@
 package pkg
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
-	"os"
-	"os/exec"
 	"testing"
+	"time"
 
 	"github.com/go-git/go-git/v5/config"
 
 	"github.com/go-git/go-git/v5"
 
 	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/rs/zerolog"
 
 	"github.com/stretchr/testify/mock"
 
 	"github.com/stretchr/testify/assert"
 )
 
-func Test_contains(t *testing.T) {
-	type args struct {
-		s []string
-		e string
-	}
-	tests := []struct {
-		name  string
-		args  args
-		want  string
-		want1 bool
-	}{
-		{"test-contains", args{
-			s: []string{"v1.1.2", "v.1.1.9"},
-			e: "/head/release/v1.1.2",
-		}, "v1.1.2", true},
-		{"test-not-contains", args{
-			s: []string{"v1.1.2", "v.1.1.9"},
-			e: "head/release/v1.1.8",
-		}, "", false},
-	}
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			actualString, actualBool := contains(tt.args.s, tt.args.e)
-			assert.Equal(t, tt.want, actualString)
-			assert.Equal(t, tt.want1, actualBool)
-
-		})
-	}
-}
-
 func TestFilterBranches(t *testing.T) {
 	type args struct {
 		branches []string
@@ -87,18 +62,27 @@ func TestFilterBranches(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			assert.Equal(t, tt.want, FilterBranches(tt.args.branches))
+			assert.Equal(t, tt.want, FilterBranches(tt.args.branches, DefaultRetentionPolicy()))
 		})
 	}
 }
 
 type remoteBranchMock struct {
 	mock.Mock
+	pushOptions *git.PushOptions
+	pushErr     error
 }
 
 func (m *remoteBranchMock) Push(options *git.PushOptions) error {
 	fmt.Println("Mocked Push function")
-	return nil
+	m.pushOptions = options
+	return m.pushErr
+}
+
+func (m *remoteBranchMock) Fetch(options *git.FetchOptions) error {
+	fmt.Println("Mocked Fetch function")
+	args := m.Called(options)
+	return args.Error(0)
 }
 
 func (m *remoteBranchMock) Config() *config.RemoteConfig {
@@ -110,7 +94,11 @@ func (m *remoteBranchMock) Config() *config.RemoteConfig {
 func (m *remoteBranchMock) List(l *git.ListOptions) ([]*plumbing.Reference, error) {
 	fmt.Println("Mocked List function")
 	args := m.Called(l)
-	return args.Get(0).([]*plumbing.Reference), nil
+	refs, _ := args.Get(0).([]*plumbing.Reference)
+	if len(args) > 1 {
+		return refs, args.Error(1)
+	}
+	return refs, nil
 }
 
 func TestGetRemoteBranches(t *testing.T) {
@@ -125,7 +113,8 @@ func TestGetRemoteBranches(t *testing.T) {
 
 	remote.On("List", &git.ListOptions{}).Return([]*plumbing.Reference{ref, ref2, ref3, ref4, ref5}, nil)
 
-	foundBranches := mockRemoteBranch.GetRemoteBranches("https://github.com/fhopfensperger/amqp-sb-client.git", "release", false)
+	foundBranches, err := mockRemoteBranch.GetRemoteBranches("https://github.com/fhopfensperger/amqp-sb-client.git", "release", false)
+	assert.NoError(t, err)
 	remote.AssertExpectations(t)
 
 	assert.Equal(t, "refs/heads/release/v1.0.0", foundBranches[0])
@@ -149,30 +138,189 @@ func TestGetRemoteBranches_latest(t *testing.T) {
 
 	remote.On("List", &git.ListOptions{}).Return([]*plumbing.Reference{ref1, ref2, ref3, ref4, ref5, ref6}, nil)
 
-	foundBranches := mockRemoteBranch.GetRemoteBranches("https://github.com/fhopfensperger/amqp-sb-client.git", "release", true)
+	foundBranches, err := mockRemoteBranch.GetRemoteBranches("https://github.com/fhopfensperger/amqp-sb-client.git", "release", true)
+	assert.NoError(t, err)
 	remote.AssertExpectations(t)
 
 	assert.Equal(t, "refs/heads/release/v11.0.1", foundBranches[0])
 }
 
-// Test exit status 1 if no branchFilter is defined
+// storeCommit stores a commit with the given committer date and parents, as a fetch would do
+func storeCommit(s *memory.Storage, when time.Time, parents ...plumbing.Hash) plumbing.Hash {
+	tree := s.NewEncodedObject()
+	_ = (&object.Tree{}).Encode(tree)
+	treeHash, _ := s.SetEncodedObject(tree)
+
+	commit := &object.Commit{
+		TreeHash:     treeHash,
+		Author:       object.Signature{Name: "test", Email: "test@example.com", When: when},
+		Committer:    object.Signature{Name: "test", Email: "test@example.com", When: when},
+		Message:      "test",
+		ParentHashes: parents,
+	}
+	obj := s.NewEncodedObject()
+	_ = commit.Encode(obj)
+	hash, _ := s.SetEncodedObject(obj)
+	return hash
+}
+
+func TestRemoteBranch_CommitDates(t *testing.T) {
+	remote := new(remoteBranchMock)
+	mockRemoteBranch := New(remote, nil)
+
+	oldDate := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	newDate := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	ref1 := plumbing.NewHashReference("refs/heads/release/v1.0.0", storeCommit(mockRemoteBranch.storage, oldDate))
+	ref2 := plumbing.NewHashReference("refs/heads/release/v1.1.0", storeCommit(mockRemoteBranch.storage, newDate))
+	ref3 := plumbing.NewHashReference("refs/heads/release/v1.2.0", plumbing.NewHash("1111111111111111111111111111111111111111"))
+
+	remote.On("List", &git.ListOptions{}).Return([]*plumbing.Reference{ref1, ref2, ref3}, nil)
+	remote.On("Fetch", &git.FetchOptions{
+		RefSpecs: []config.RefSpec{
+			"+refs/heads/release/v1.0.0:refs/fetched/heads/release/v1.0.0",
+			"+refs/heads/release/v1.1.0:refs/fetched/heads/release/v1.1.0",
+			"+refs/heads/release/v1.2.0:refs/fetched/heads/release/v1.2.0",
+		},
+		Depth: 1,
+		Tags:  git.NoTags,
+	}).Return(nil)
+
+	branches, err := mockRemoteBranch.GetRemoteBranches("https://github.com/fhopfensperger/amqp-sb-client.git", "release", false)
+	assert.NoError(t, err)
+	dates, err := mockRemoteBranch.CommitDates(branches)
+	assert.NoError(t, err)
+	remote.AssertExpectations(t)
+
+	assert.Len(t, dates, 2)
+	assert.True(t, oldDate.Equal(dates["refs/heads/release/v1.0.0"]))
+	assert.True(t, newDate.Equal(dates["refs/heads/release/v1.1.0"]))
+}
+
+func TestRemoteBranch_MergedBranches(t *testing.T) {
+	remote := new(remoteBranchMock)
+	mockRemoteBranch := New(remote, nil)
+
+	date := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	mergedRelease := storeCommit(mockRemoteBranch.storage, date)
+	unmergedRelease := storeCommit(mockRemoteBranch.storage, date.AddDate(0, 0, 1), mergedRelease)
+	main := storeCommit(mockRemoteBranch.storage, date.AddDate(0, 0, 3), storeCommit(mockRemoteBranch.storage, date.AddDate(0, 0, 2), mergedRelease))
+
+	refs := []*plumbing.Reference{
+		plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/main"),
+		plumbing.NewHashReference("refs/heads/main", main),
+		plumbing.NewHashReference("refs/heads/release/v1.0.0", mergedRelease),
+		plumbing.NewHashReference("refs/heads/release/v1.1.0", unmergedRelease),
+	}
+	remote.On("List", &git.ListOptions{}).Return(refs, nil)
+	remote.On("Fetch", &git.FetchOptions{
+		RefSpecs: []config.RefSpec{"+refs/heads/main:refs/fetched/heads/main"},
+		Depth:    unshallowDepth,
+		Tags:     git.NoTags,
+	}).Return(nil)
+
+	branches, err := mockRemoteBranch.GetRemoteBranches("https://github.com/fhopfensperger/amqp-sb-client.git", "release", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "refs/heads/main", mockRemoteBranch.DefaultBranch())
+	merged, unmerged, err := mockRemoteBranch.MergedBranches(branches)
+	assert.NoError(t, err)
+	remote.AssertExpectations(t)
+
+	assert.Equal(t, []string{"refs/heads/release/v1.0.0"}, merged)
+	assert.Equal(t, []string{"refs/heads/release/v1.1.0"}, unmerged)
+}
+
+func TestRemoteBranch_MergedBranches_no_default_branch(t *testing.T) {
+	remote := new(remoteBranchMock)
+	mockRemoteBranch := New(remote, nil)
+
+	remote.On("List", &git.ListOptions{}).Return([]*plumbing.Reference{
+		plumbing.NewHashReference("refs/heads/release/v1.0.0", plumbing.ZeroHash),
+	}, nil)
+
+	branches, err := mockRemoteBranch.GetRemoteBranches("https://github.com/fhopfensperger/amqp-sb-client.git", "release", false)
+	assert.NoError(t, err)
+	merged, unmerged, err := mockRemoteBranch.MergedBranches(branches)
+	assert.ErrorIs(t, err, ErrNoDefaultBranch)
+
+	assert.Empty(t, merged)
+	assert.Equal(t, []string{"refs/heads/release/v1.0.0"}, unmerged)
+	remote.AssertNotCalled(t, "Fetch", mock.Anything)
+}
+
+func Test_defaultBranch(t *testing.T) {
+	hash := plumbing.NewHash("1111111111111111111111111111111111111111")
+	tests := []struct {
+		name string
+		refs []*plumbing.Reference
+		want string
+	}{
+		{"symbolic-head", []*plumbing.Reference{
+			plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/main"),
+			plumbing.NewHashReference("refs/heads/main", hash),
+		}, "refs/heads/main"},
+		{"hash-head", []*plumbing.Reference{
+			plumbing.NewHashReference(plumbing.HEAD, hash),
+			plumbing.NewHashReference("refs/heads/release/v1.0.0", plumbing.ZeroHash),
+			plumbing.NewHashReference("refs/heads/develop", hash),
+		}, "refs/heads/develop"},
+		{"no-head", []*plumbing.Reference{
+			plumbing.NewHashReference("refs/heads/main", hash),
+		}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, defaultBranch(tt.refs))
+		})
+	}
+}
+
 func TestGetRemoteBranchesNoBranchFilter(t *testing.T) {
-	if os.Getenv("FLAG") == "1" {
-		remote := new(remoteBranchMock)
-		mockRemoteBranch := New(remote, nil)
-		mockRemoteBranch.GetRemoteBranches("https://github.com/fhopfensperger/amqp-sb-client.git", "", false)
-		return
+	remote := new(remoteBranchMock)
+	mockRemoteBranch := New(remote, nil)
+	branches, err := mockRemoteBranch.GetRemoteBranches("https://github.com/fhopfensperger/amqp-sb-client.git", "", false)
+
+	assert.ErrorIs(t, err, ErrNoBranchFilter)
+	assert.Nil(t, branches)
+	remote.AssertNotCalled(t, "List", mock.Anything)
+}
+
+func TestGetRemoteBranches_error(t *testing.T) {
+	tests := []struct {
+		name    string
+		listErr error
+		want    error
+	}{
+		{"authentication", transport.ErrAuthenticationRequired, ErrAuthentication},
+		{"authorization", transport.ErrAuthorizationFailed, ErrAuthentication},
+		{"not-found", transport.ErrRepositoryNotFound, ErrRepositoryNotFound},
+		{"empty", transport.ErrEmptyRemoteRepository, ErrEmptyRepository},
 	}
-	// Run the test in a subprocess
-	cmd := exec.Command(os.Args[0], "-test.run=TestGetRemoteBranchesNoBranchFilter")
-	cmd.Env = append(os.Environ(), "FLAG=1")
-	err := cmd.Run()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			remote := new(remoteBranchMock)
+			mockRemoteBranch := New(remote, nil)
+			remote.On("List", &git.ListOptions{}).Return(nil, tt.listErr)
+
+			branches, err := mockRemoteBranch.GetRemoteBranches("https://github.com/fhopfensperger/amqp-sb-client.git", "release", false)
 
-	// Cast the error as *exec.ExitError and compare the result
-	e, ok := err.(*exec.ExitError)
-	expectedErrorString := "exit status 1"
-	assert.Equal(t, true, ok)
-	assert.Equal(t, expectedErrorString, e.Error())
+			assert.ErrorIs(t, err, tt.want)
+			assert.ErrorIs(t, err, tt.listErr)
+			assert.Nil(t, branches)
+		})
+	}
+}
+
+func TestGetRemoteBranches_latest_no_branches(t *testing.T) {
+	remote := new(remoteBranchMock)
+	mockRemoteBranch := New(remote, nil)
+	remote.On("List", &git.ListOptions{}).Return([]*plumbing.Reference{
+		plumbing.NewHashReference("refs/heads/main", plumbing.ZeroHash),
+	}, nil)
+
+	branches, err := mockRemoteBranch.GetRemoteBranches("https://github.com/fhopfensperger/amqp-sb-client.git", "release", true)
+
+	assert.NoError(t, err)
+	assert.Empty(t, branches)
 }
 
 func TestRemoteBranch_CleanBranches(t *testing.T) {
@@ -195,10 +343,25 @@ func TestRemoteBranch_CleanBranches(t *testing.T) {
 
 	remote.On("Config").Return(&remoteConfing)
 	remote.On("Push", &pushOptions).Return(nil)
-	deletedBranches := mockRemoteBranch.CleanBranches([]string{"refs/heads/release/v2.2.2"}, []string{"v2.2.1"}, false)
+	deletedBranches, err := mockRemoteBranch.CleanBranches([]string{"refs/heads/release/v2.2.2"}, []string{"v2.2.1"}, false)
+	assert.NoError(t, err)
 	assert.Equal(t, []string{"refs/heads/release/v2.2.2"}, deletedBranches)
 }
 
+func TestRemoteBranch_CleanBranches_push_rejected(t *testing.T) {
+	remote := &remoteBranchMock{pushErr: errors.New("remote ref refs/heads/release/v2.2.2 required to be 1111 but is 2222")}
+	mockRemoteBranch := New(remote, nil)
+	remote.On("Config").Return(&config.RemoteConfig{
+		Name: "amqp-sb-client.git",
+		URLs: []string{"https://github.com/fhopfensperger/amqp-sb-client.git"},
+	})
+
+	deletedBranches, err := mockRemoteBranch.CleanBranches([]string{"refs/heads/release/v2.2.2"}, nil, false)
+
+	assert.ErrorIs(t, err, ErrPushRejected)
+	assert.Nil(t, deletedBranches)
+}
+
 func TestRemoteBranch_CleanBranches_All_Excluded(t *testing.T) {
 	remote := new(remoteBranchMock)
 	// Actually we need to use a real Repo, because inside CleanBranches we need a real repo
@@ -220,7 +383,8 @@ func TestRemoteBranch_CleanBranches_All_Excluded(t *testing.T) {
 
 	remote.On("Config").Return(&remoteConfing)
 	remote.On("Push", &pushOptions).Return(nil)
-	deletedBranches := mockRemoteBranch.CleanBranches([]string{"refs/heads/release/v2.2.2", "refs/heads/release/v2.2.1"}, []string{"v2.2.1", "v2.2.2"}, false)
+	deletedBranches, err := mockRemoteBranch.CleanBranches([]string{"refs/heads/release/v2.2.2", "refs/heads/release/v2.2.1"}, []string{"v2.2.1", "v2.2.2"}, false)
+	assert.NoError(t, err)
 	assert.Empty(t, deletedBranches)
 }
 
@@ -245,7 +409,8 @@ func TestRemoteBranch_CleanBranches_Some_Excluded(t *testing.T) {
 
 	remote.On("Config").Return(&remoteConfing)
 	remote.On("Push", &pushOptions).Return(nil)
-	deletedBranches := mockRemoteBranch.CleanBranches([]string{"refs/heads/release/v2.2.3", "refs/heads/release/v2.2.2", "refs/heads/release/v2.2.1"}, []string{"v2.2.2"}, false)
+	deletedBranches, err := mockRemoteBranch.CleanBranches([]string{"refs/heads/release/v2.2.3", "refs/heads/release/v2.2.2", "refs/heads/release/v2.2.1"}, []string{"v2.2.2"}, false)
+	assert.NoError(t, err)
 	assert.Equal(t, []string{"refs/heads/release/v2.2.3", "refs/heads/release/v2.2.1"}, deletedBranches)
 }
 
@@ -270,6 +435,214 @@ func TestRemoteBranch_CleanBranches_branches_to_delete_empty(t *testing.T) {
 
 	remote.On("Config").Return(&remoteConfing)
 	remote.On("Push", &pushOptions).Return(nil)
-	deletedBranches := mockRemoteBranch.CleanBranches([]string{}, []string{"v2.2.2"}, false)
+	deletedBranches, err := mockRemoteBranch.CleanBranches([]string{}, []string{"v2.2.2"}, false)
+	assert.NoError(t, err)
 	assert.Empty(t, deletedBranches)
 }
+
+func TestRemoteBranch_CleanBranches_archive_as_tag(t *testing.T) {
+	remote := new(remoteBranchMock)
+	mockRemoteBranch := New(remote, nil, WithArchiveTags("archive/"))
+
+	tip := storeCommit(mockRemoteBranch.storage, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	remote.On("List", &git.ListOptions{}).Return([]*plumbing.Reference{
+		plumbing.NewHashReference("refs/heads/release/v1.1.0", tip),
+	}, nil)
+	remote.On("Fetch", mock.Anything).Return(nil)
+	remote.On("Config").Return(&config.RemoteConfig{
+		Name: "amqp-sb-client.git",
+		URLs: []string{"https://github.com/fhopfensperger/amqp-sb-client.git"},
+	})
+
+	branches, err := mockRemoteBranch.GetRemoteBranches("https://github.com/fhopfensperger/amqp-sb-client.git", "release", false)
+	assert.NoError(t, err)
+	deletedBranches, err := mockRemoteBranch.CleanBranches(branches, nil, false)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"refs/heads/release/v1.1.0"}, deletedBranches)
+	assert.Equal(t, []config.RefSpec{
+		"refs/heads/release/v1.1.0:refs/heads/release/v1.1.0",
+		"refs/tags/archive/release/v1.1.0:refs/tags/archive/release/v1.1.0",
+	}, remote.pushOptions.RefSpecs)
+	tag, err := mockRemoteBranch.storage.Reference("refs/tags/archive/release/v1.1.0")
+	assert.NoError(t, err)
+	assert.Equal(t, tip, tag.Hash())
+}
+
+func TestRemoteBranch_CleanBranches_archive_as_tag_unknown_tip(t *testing.T) {
+	remote := new(remoteBranchMock)
+	mockRemoteBranch := New(remote, nil, WithArchiveTags("archive/"))
+
+	remote.On("Fetch", mock.Anything).Return(nil)
+	remote.On("Config").Return(&config.RemoteConfig{
+		Name: "amqp-sb-client.git",
+		URLs: []string{"https://github.com/fhopfensperger/amqp-sb-client.git"},
+	})
+
+	deletedBranches, err := mockRemoteBranch.CleanBranches([]string{"refs/heads/release/v1.1.0"}, nil, false)
+	assert.Error(t, err)
+
+	assert.Empty(t, deletedBranches)
+	assert.Nil(t, remote.pushOptions)
+}
+
+func TestRemoteBranch_CleanBranches_skip_moved_branches(t *testing.T) {
+	remote := new(remoteBranchMock)
+	mockRemoteBranch := New(remote, nil)
+
+	listed := plumbing.NewHash("1111111111111111111111111111111111111111")
+	moved := plumbing.NewHash("2222222222222222222222222222222222222222")
+	remote.On("List", &git.ListOptions{}).Return([]*plumbing.Reference{
+		plumbing.NewHashReference("refs/heads/release/v1.0.0", listed),
+		plumbing.NewHashReference("refs/heads/release/v1.0.1", listed),
+		plumbing.NewHashReference("refs/heads/release/v1.0.2", listed),
+	}, nil).Once()
+	remote.On("List", &git.ListOptions{}).Return([]*plumbing.Reference{
+		plumbing.NewHashReference("refs/heads/release/v1.0.0", listed),
+		plumbing.NewHashReference("refs/heads/release/v1.0.1", moved),
+	}, nil).Once()
+	remote.On("Config").Return(&config.RemoteConfig{
+		Name: "amqp-sb-client.git",
+		URLs: []string{"https://github.com/fhopfensperger/amqp-sb-client.git"},
+	})
+
+	branches, err := mockRemoteBranch.GetRemoteBranches("https://github.com/fhopfensperger/amqp-sb-client.git", "release", false)
+	assert.NoError(t, err)
+	deletedBranches, err := mockRemoteBranch.CleanBranches(branches, nil, false)
+	assert.NoError(t, err)
+	remote.AssertExpectations(t)
+
+	assert.Equal(t, []string{"refs/heads/release/v1.0.0"}, deletedBranches)
+	assert.Equal(t, []config.RefSpec{"refs/heads/release/v1.0.0:refs/heads/release/v1.0.0"}, remote.pushOptions.RefSpecs)
+	assert.Equal(t, []config.RefSpec{"1111111111111111111111111111111111111111:refs/heads/release/v1.0.0"}, remote.pushOptions.RequireRemoteRefs)
+}
+
+func TestRemoteBranch_CleanBranches_all_branches_moved(t *testing.T) {
+	remote := new(remoteBranchMock)
+	mockRemoteBranch := New(remote, nil)
+
+	remote.On("List", &git.ListOptions{}).Return([]*plumbing.Reference{
+		plumbing.NewHashReference("refs/heads/release/v1.0.0", plumbing.NewHash("1111111111111111111111111111111111111111")),
+	}, nil).Once()
+	remote.On("List", &git.ListOptions{}).Return([]*plumbing.Reference{
+		plumbing.NewHashReference("refs/heads/release/v1.0.0", plumbing.NewHash("2222222222222222222222222222222222222222")),
+	}, nil).Once()
+	remote.On("Config").Return(&config.RemoteConfig{
+		Name: "amqp-sb-client.git",
+		URLs: []string{"https://github.com/fhopfensperger/amqp-sb-client.git"},
+	})
+
+	branches, err := mockRemoteBranch.GetRemoteBranches("https://github.com/fhopfensperger/amqp-sb-client.git", "release", false)
+	assert.NoError(t, err)
+	deletedBranches, err := mockRemoteBranch.CleanBranches(branches, nil, false)
+	assert.NoError(t, err)
+
+	assert.Empty(t, deletedBranches)
+	assert.Nil(t, remote.pushOptions)
+}
+
+func TestExcludeBranches(t *testing.T) {
+	branches, excluded, err := ExcludeBranches([]string{"refs/heads/release/v2.2.2", "refs/heads/release/v2.2.1"}, []string{"v2.2.1"}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"refs/heads/release/v2.2.2"}, branches)
+	assert.Equal(t, map[string]string{"refs/heads/release/v2.2.1": "v2.2.1"}, excluded)
+}
+
+func TestVersion(t *testing.T) {
+	assert.Equal(t, "v1.1.0", Version("refs/heads/release/v1.1.0"))
+	assert.Equal(t, "", Version("refs/heads/master"))
+}
+
+func TestRemoteBranch_SHA(t *testing.T) {
+	remote := new(remoteBranchMock)
+	mockRemoteBranch := New(remote, nil)
+	hash := plumbing.NewHash("1111111111111111111111111111111111111111")
+	remote.On("List", &git.ListOptions{}).Return([]*plumbing.Reference{
+		plumbing.NewHashReference("refs/heads/release/v1.0.0", hash),
+	}, nil)
+
+	_, err := mockRemoteBranch.GetRemoteBranches("https://github.com/fhopfensperger/amqp-sb-client.git", "release", false)
+	assert.NoError(t, err)
+
+	assert.Equal(t, hash.String(), mockRemoteBranch.SHA("refs/heads/release/v1.0.0"))
+	assert.Equal(t, "", mockRemoteBranch.SHA("refs/heads/release/v1.0.1"))
+}
+
+func TestNew_WithLogger(t *testing.T) {
+	remote := new(remoteBranchMock)
+	var logs bytes.Buffer
+	mockRemoteBranch := New(remote, nil, WithLogger(zerolog.New(&logs)))
+	remote.On("List", &git.ListOptions{}).Return([]*plumbing.Reference{
+		plumbing.NewHashReference("refs/heads/release/v1.0.0", plumbing.ZeroHash),
+	}, nil)
+
+	_, err := mockRemoteBranch.GetRemoteBranches("https://github.com/fhopfensperger/amqp-sb-client.git", "release", false)
+
+	assert.NoError(t, err)
+	assert.Contains(t, logs.String(), "Remote branches found: [refs/heads/release/v1.0.0]")
+}
+
+// storeTag stores an annotated tag of the commit
+func storeTag(s *memory.Storage, name string, commit plumbing.Hash) plumbing.Hash {
+	tag := &object.Tag{
+		Name:       name,
+		Tagger:     object.Signature{Name: "test", Email: "test@example.com", When: time.Now()},
+		Message:    "test",
+		TargetType: plumbing.CommitObject,
+		Target:     commit,
+	}
+	obj := s.NewEncodedObject()
+	_ = tag.Encode(obj)
+	hash, _ := s.SetEncodedObject(obj)
+	return hash
+}
+
+func TestRemoteBranch_tags(t *testing.T) {
+	remote := new(remoteBranchMock)
+	mockRemoteBranch := New(remote, nil, WithTags(DefaultArchiveTagPrefix))
+
+	date := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	commit := storeCommit(mockRemoteBranch.storage, date)
+	annotated := storeTag(mockRemoteBranch.storage, "v1.1.0", commit)
+	remote.On("List", &git.ListOptions{}).Return([]*plumbing.Reference{
+		plumbing.NewSymbolicReference(plumbing.HEAD, "refs/heads/master"),
+		plumbing.NewHashReference("refs/heads/master", commit),
+		plumbing.NewHashReference("refs/heads/release/v1.0.0", commit),
+		plumbing.NewHashReference("refs/tags/v1.0.0", commit),
+		plumbing.NewHashReference("refs/tags/v1.1.0", annotated),
+		plumbing.NewHashReference("refs/tags/archive/release/v1.0.0", commit),
+	}, nil)
+	remote.On("Fetch", mock.Anything).Return(nil)
+
+	tags, err := mockRemoteBranch.GetRemoteBranches("https://github.com/fhopfensperger/amqp-sb-client.git", "v1", false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"refs/tags/v1.0.0", "refs/tags/v1.1.0"}, tags)
+
+	// Annotated tags are peeled to the dates and the merge state of their commits
+	dates, err := mockRemoteBranch.CommitDates(tags)
+	assert.NoError(t, err)
+	assert.True(t, date.Equal(dates["refs/tags/v1.1.0"]))
+	merged, unmerged, err := mockRemoteBranch.MergedBranches(tags)
+	assert.NoError(t, err)
+	assert.Equal(t, tags, merged)
+	assert.Empty(t, unmerged)
+}
+
+func TestRemoteBranch_tags_archive_tags_survive(t *testing.T) {
+	remote := new(remoteBranchMock)
+	mockRemoteBranch := New(remote, nil, WithTags("archive/"))
+	remote.On("Config").Return(&config.RemoteConfig{URLs: []string{"https://github.com/fhopfensperger/amqp-sb-client.git"}})
+	remote.On("List", &git.ListOptions{}).Return([]*plumbing.Reference{
+		plumbing.NewHashReference("refs/tags/v1.0.0", plumbing.NewHash("1111111111111111111111111111111111111111")),
+		plumbing.NewHashReference("refs/tags/v1.1.0", plumbing.NewHash("2222222222222222222222222222222222222222")),
+		plumbing.NewHashReference("refs/tags/archive/release/v1.0.0", plumbing.NewHash("3333333333333333333333333333333333333333")),
+	}, nil)
+
+	tags, err := mockRemoteBranch.GetRemoteBranches("https://github.com/fhopfensperger/amqp-sb-client.git", "v", false)
+	assert.NoError(t, err)
+	deleted, err := mockRemoteBranch.CleanBranches(FilterBranches(tags, RetentionPolicy{Majors: 1, Minors: 1}), nil, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"refs/tags/v1.0.0"}, deleted)
+	assert.Equal(t, []config.RefSpec{"refs/tags/v1.0.0:refs/tags/v1.0.0"}, remote.pushOptions.RefSpecs)
+}
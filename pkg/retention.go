@@ -0,0 +1,148 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"regexp"
+	"time"
+)
+
+// RetentionPolicy defines which branches are kept by FilterBranches. A limit of 0 means unlimited,
+// e.g. Patches: 2 keeps the two latest patch branches of every minor version.
+type RetentionPolicy struct {
+	// Patches is the number of latest patch versions kept per minor version
+	Patches int
+	// Minors is the number of latest minor versions kept per major version
+	Minors int
+	// Majors is the number of latest major versions kept
+	Majors int
+	// Months is the number of latest minor versions kept across all major versions, with the CalVerParser these are
+	// the latest months with branches
+	Months int
+	// KeepNewest always keeps the newest branch, regardless of the other rules
+	KeepNewest bool
+	// DeletePrereleases deletes the pre-releases of versions which are released, e.g. v2.0.0-rc.1 if v2.0.0 exists
+	DeletePrereleases bool
+	// DeleteUnversioned deletes branches without a version, e.g. release/next, they are always kept otherwise
+	DeleteUnversioned bool
+	// OlderThan deletes branches whose last commit is older, regardless of their version
+	OlderThan time.Duration
+	// KeepNewerThan keeps branches whose last commit is newer, regardless of their version
+	KeepNewerThan time.Duration
+	// Versions parses the versions of the branches, the SemVerParser if nil
+	Versions VersionParser
+	// GroupBy groups the branches by component, the policy is applied to every component on its own, see ParseGroupBy
+	GroupBy *regexp.Regexp
+}
+
+// UsesCommitDates reports if the policy needs the last commit dates of the branches
+func (p RetentionPolicy) UsesCommitDates() bool {
+	return p.OlderThan > 0 || p.KeepNewerThan > 0
+}
+
+// DefaultRetentionPolicy keeps the latest patch version of every minor version
+func DefaultRetentionPolicy() RetentionPolicy {
+	return RetentionPolicy{Patches: 1, KeepNewest: true}
+}
+
+// kept returns the branches of the sorted branches slice which are kept by the policy, it is applied to every
+// component on its own
+func (p RetentionPolicy) kept(branches []string) map[string]bool {
+	kept := map[string]bool{}
+	for _, group := range GroupByComponent(branches, p.GroupBy) {
+		for i, k := range p.keeps(group) {
+			if k {
+				kept[group[i]] = true
+			}
+		}
+	}
+	return kept
+}
+
+// keeps reports for every branch of the sorted branches slice of a component if it is kept by the policy
+func (p RetentionPolicy) keeps(branches []string) []bool {
+	kept := make([]bool, len(branches))
+
+	majorRanks := map[int]int{}
+	minorRanks := map[[2]int]int{}
+	monthRanks := map[[2]int]int{}
+	minorsPerMajor := map[int]int{}
+	patchesPerMinor := map[[2]int]int{}
+	// Pre-releases newer than every release of their minor version are ranked on their own, so a release candidate
+	// never takes the place of the latest release, e.g. v2.0.2-rc.1 does not delete v2.0.1
+	upcomingPerMinor := map[[2]int]int{}
+
+	unversioned := toSet(Unversioned(branches, p.Versions))
+
+	// Walk from the newest to the oldest branch, so the rank of a version is its position from the top
+	for i := len(branches) - 1; i >= 0; i-- {
+		// Branches without a version have no rank, they are kept or deleted all together
+		if unversioned[branches[i]] {
+			kept[i] = !p.DeleteUnversioned
+			continue
+		}
+		version := parseVersion(p.Versions, branches[i])
+		major := version.part(0)
+		minor := [2]int{major, version.part(1)}
+
+		if _, ok := majorRanks[major]; !ok {
+			majorRanks[major] = len(majorRanks) + 1
+		}
+		if _, ok := minorRanks[minor]; !ok {
+			minorsPerMajor[major]++
+			minorRanks[minor] = minorsPerMajor[major]
+			monthRanks[minor] = len(monthRanks) + 1
+		}
+		patchRank := 0
+		if version.IsPrerelease() && patchesPerMinor[minor] == 0 {
+			upcomingPerMinor[minor]++
+			patchRank = upcomingPerMinor[minor]
+		} else {
+			patchesPerMinor[minor]++
+			patchRank = patchesPerMinor[minor]
+		}
+
+		kept[i] = withinLimit(p.Majors, majorRanks[major]) &&
+			withinLimit(p.Minors, minorRanks[minor]) &&
+			withinLimit(p.Months, monthRanks[minor]) &&
+			withinLimit(p.Patches, patchRank)
+	}
+
+	if p.DeletePrereleases {
+		released := toSet(ReleasedPrereleases(branches, p.Versions))
+		for i, b := range branches {
+			kept[i] = kept[i] && !released[b]
+		}
+	}
+
+	if p.KeepNewest && len(branches) > 0 {
+		kept[len(branches)-1] = true
+	}
+	return kept
+}
+
+func toSet(s []string) map[string]bool {
+	set := make(map[string]bool, len(s))
+	for _, e := range s {
+		set[e] = true
+	}
+	return set
+}
+
+func withinLimit(limit int, rank int) bool {
+	return limit <= 0 || rank <= limit
+}
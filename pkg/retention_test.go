@@ -0,0 +1,107 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterBranches_retention_policy(t *testing.T) {
+	branches := []string{"head/release/v1.0.0", "head/release/v1.0.1", "head/release/v1.1.0", "head/release/v1.1.1",
+		"head/release/v1.1.2", "head/release/v2.0.0", "head/release/v2.0.1", "head/release/v3.0.0"}
+	tests := []struct {
+		name   string
+		policy RetentionPolicy
+		want   []string
+	}{
+		{
+			name:   "keep-two-patches",
+			policy: RetentionPolicy{Patches: 2},
+			want:   []string{"head/release/v1.1.0"},
+		},
+		{
+			name:   "keep-all-patches",
+			policy: RetentionPolicy{},
+			want:   []string{},
+		},
+		{
+			name:   "keep-latest-minor",
+			policy: RetentionPolicy{Patches: 1, Minors: 1},
+			want:   []string{"head/release/v1.0.0", "head/release/v1.0.1", "head/release/v1.1.0", "head/release/v1.1.1", "head/release/v2.0.0"},
+		},
+		{
+			name:   "keep-latest-two-majors",
+			policy: RetentionPolicy{Majors: 2},
+			want:   []string{"head/release/v1.0.0", "head/release/v1.0.1", "head/release/v1.1.0", "head/release/v1.1.1", "head/release/v1.1.2"},
+		},
+		{
+			name:   "keep-newest",
+			policy: RetentionPolicy{Patches: 1, Majors: 1, KeepNewest: true},
+			want:   []string{"head/release/v1.0.0", "head/release/v1.0.1", "head/release/v1.1.0", "head/release/v1.1.1", "head/release/v1.1.2", "head/release/v2.0.0", "head/release/v2.0.1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FilterBranches(append([]string{}, branches...), tt.policy))
+		})
+	}
+}
+
+func TestDefaultRetentionPolicy(t *testing.T) {
+	assert.Equal(t, RetentionPolicy{Patches: 1, KeepNewest: true}, DefaultRetentionPolicy())
+}
+
+func TestFilterBranches_calver(t *testing.T) {
+	branches := []string{"head/release/2023.11", "head/release/2024.1", "head/release/2023.12.1", "head/release/2023.12",
+		"head/release/2024.11.1", "head/release/2024.03", "head/release/2024.11"}
+	tests := []struct {
+		name   string
+		policy RetentionPolicy
+		want   []string
+	}{
+		{
+			name:   "latest-patch-per-month",
+			policy: RetentionPolicy{Patches: 1, Versions: CalVerParser{}},
+			want:   []string{"head/release/2023.12", "head/release/2024.11"},
+		},
+		{
+			name:   "last-three-months",
+			policy: RetentionPolicy{Months: 3, Versions: CalVerParser{}},
+			want:   []string{"head/release/2023.11", "head/release/2023.12", "head/release/2023.12.1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, FilterBranches(append([]string{}, branches...), tt.policy))
+		})
+	}
+}
+
+func TestFilterBranches_delete_prereleases(t *testing.T) {
+	branches := []string{"head/release/v2.0.0-rc.1", "head/release/v2.0.0-rc.2", "head/release/v2.0.0",
+		"head/release/v2.1.0-beta", "head/release/v2.1.0-rc.1"}
+
+	assert.Equal(t, []string{"head/release/v2.0.0-rc.1", "head/release/v2.0.0-rc.2", "head/release/v2.1.0-beta"},
+		FilterBranches(append([]string{}, branches...), RetentionPolicy{Patches: 1, KeepNewest: true}))
+	assert.Equal(t, []string{"head/release/v2.0.0-rc.1", "head/release/v2.0.0-rc.2"},
+		FilterBranches(append([]string{}, branches...), RetentionPolicy{DeletePrereleases: true}))
+}
+
+func TestFilterBranches_prerelease_keeps_release(t *testing.T) {
+	branches := []string{"head/release/v2.0.0", "head/release/v2.0.1", "head/release/v2.0.2-rc.1", "head/release/v2.0.2-rc.2",
+		"head/release/v2.0.1-rc.1"}
+
+	assert.Equal(t, []string{"head/release/v2.0.0", "head/release/v2.0.1-rc.1", "head/release/v2.0.2-rc.1"},
+		FilterBranches(append([]string{}, branches...), DefaultRetentionPolicy()))
+	assert.Equal(t, []string{"head/release/v2.0.0"},
+		FilterBranches(append([]string{}, branches...), RetentionPolicy{Patches: 2}))
+}
+
+func TestFilterBranches_unversioned(t *testing.T) {
+	branches := []string{"head/release/v1.0.0", "head/release/next", "head/release/v1.0.1", "head/release/candidate"}
+
+	assert.Equal(t, []string{"head/release/v1.0.0"},
+		FilterBranches(append([]string{}, branches...), DefaultRetentionPolicy()))
+	assert.Equal(t, []string{"head/release/next", "head/release/candidate", "head/release/v1.0.0"},
+		FilterBranches(append([]string{}, branches...), RetentionPolicy{Patches: 1, KeepNewest: true, DeleteUnversioned: true}))
+}
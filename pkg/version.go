@@ -0,0 +1,281 @@
+/*
+Copyright © 2020 Florian Hopfensperger <f.hopfensperger@gmail.com>
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pkg
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParsedVersion is the version of a branch, e.g. v1.2.3 of refs/heads/release/v1.2.3
+type ParsedVersion struct {
+	// Original is the version as written in the branch name, e.g. v1.02.3
+	Original string
+	// Parts are the numeric parts of the version, e.g. [1 2 3]
+	Parts []int
+	// Prerelease is the pre-release of the version without the dash, e.g. rc.1 of v2.0.0-rc.1
+	Prerelease string
+	// Build is the build metadata of the version without the plus, e.g. build.5 of v2.0.0+build.5
+	Build string
+}
+
+// Compare returns -1, 0 or +1 if v is lower, equal or higher than o. Missing parts are 0, so 1.2 equals 1.2.0.
+// Like semver, a pre-release is lower than its release and the build metadata is ignored.
+func (v ParsedVersion) Compare(o ParsedVersion) int {
+	if cmp := v.compareParts(o); cmp != 0 {
+		return cmp
+	}
+	return comparePrerelease(v.Prerelease, o.Prerelease)
+}
+
+// compareParts compares only the numeric parts of the versions
+func (v ParsedVersion) compareParts(o ParsedVersion) int {
+	n := len(v.Parts)
+	if len(o.Parts) > n {
+		n = len(o.Parts)
+	}
+	for i := 0; i < n; i++ {
+		switch a, b := v.part(i), o.part(i); {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	}
+	return 0
+}
+
+// IsPrerelease reports if the version is a pre-release, e.g. v2.0.0-rc.1
+func (v ParsedVersion) IsPrerelease() bool {
+	return v.Prerelease != ""
+}
+
+// comparePrerelease compares pre-releases like semver: no pre-release is higher than any, numeric identifiers are
+// compared as numbers and are lower than other identifiers, and more identifiers are higher if all others are equal
+func comparePrerelease(a string, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil && an != bn:
+			return compareInts(an, bn)
+		case aErr == nil && bErr != nil:
+			return -1
+		case aErr != nil && bErr == nil:
+			return 1
+		case aErr != nil && as[i] != bs[i]:
+			return strings.Compare(as[i], bs[i])
+		}
+	}
+	return compareInts(len(as), len(bs))
+}
+
+func compareInts(a int, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// part returns the part with the index i, 0 if the version has less parts
+func (v ParsedVersion) part(i int) int {
+	if i < len(v.Parts) {
+		return v.Parts[i]
+	}
+	return 0
+}
+
+func (v ParsedVersion) String() string {
+	return v.Original
+}
+
+// VersionParser extracts the version of a branch name, the versions decide how branches are sorted and grouped by the
+// retention policy
+type VersionParser interface {
+	// Parse returns the version of the branch, false if the branch has no version
+	Parse(branch string) (ParsedVersion, bool)
+}
+
+// SemVerParser parses versions with two to four numeric parts like v1.2.3, the v prefix is optional and leading zeros
+// are allowed, e.g. release/1.4.2, release/v1.2.3.4 or release/v1.02.0. A pre-release and build metadata are parsed
+// like semver, e.g. release/v2.0.0-rc.1+build.5.
+type SemVerParser struct{}
+
+var versionRegex = regexp.MustCompile(`v?(\d+(?:\.\d+)+)(?:-([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?(?:\+([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?`)
+
+// Parse returns the first version of the branch
+func (SemVerParser) Parse(branch string) (ParsedVersion, bool) {
+	for _, v := range versionCandidates(branch) {
+		if len(v.Parts) <= 4 {
+			return v, true
+		}
+	}
+	return ParsedVersion{}, false
+}
+
+// CalVerParser parses calendar versions like 2024.03 or 2024.11.1, with a four digit year, the month and an optional
+// patch number. The months are the minor versions of the retention policy, e.g. Patches: 1 keeps the latest patch per
+// month and Months: 6 keeps the last six months.
+type CalVerParser struct{}
+
+// Parse returns the first calendar version of the branch
+func (CalVerParser) Parse(branch string) (ParsedVersion, bool) {
+	for _, v := range versionCandidates(branch) {
+		year := strings.SplitN(strings.TrimPrefix(v.Original, "v"), ".", 2)[0]
+		if len(year) == 4 && len(v.Parts) <= 3 && v.Parts[1] >= 1 && v.Parts[1] <= 12 {
+			return v, true
+		}
+	}
+	return ParsedVersion{}, false
+}
+
+// versionCandidates returns the dotted numbers of the branch, which can be versions. A number without the v prefix
+// must not be part of a word, so the 1.2 of build1.2 is no candidate.
+func versionCandidates(branch string) []ParsedVersion {
+	var candidates []ParsedVersion
+	for _, loc := range versionRegex.FindAllStringSubmatchIndex(branch, -1) {
+		s := branch[loc[0]:loc[1]]
+		if !strings.HasPrefix(s, "v") && loc[0] > 0 && isWordChar(branch[loc[0]-1]) {
+			continue
+		}
+		parts, err := parseNumericParts(branch[loc[2]:loc[3]])
+		if err != nil {
+			continue
+		}
+		v := ParsedVersion{Original: s, Parts: parts}
+		if loc[4] >= 0 {
+			v.Prerelease = branch[loc[4]:loc[5]]
+		}
+		if loc[6] >= 0 {
+			v.Build = branch[loc[6]:loc[7]]
+		}
+		candidates = append(candidates, v)
+	}
+	return candidates
+}
+
+// ParseVersionScheme returns the parser of the version scheme semver or calver
+func ParseVersionScheme(scheme string) (VersionParser, error) {
+	switch scheme {
+	case "", "semver":
+		return SemVerParser{}, nil
+	case "calver":
+		return CalVerParser{}, nil
+	}
+	return nil, fmt.Errorf("invalid version scheme %q, must be semver or calver", scheme)
+}
+
+func isWordChar(c byte) bool {
+	return c == '.' || c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// parseNumericParts parses the parts of a version like 1.02.3 into [1 2 3]
+func parseNumericParts(s string) ([]int, error) {
+	var parts []int
+	for _, p := range strings.Split(s, ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, n)
+	}
+	return parts, nil
+}
+
+// versionParserOr returns the parser, or the SemVerParser if it is nil
+func versionParserOr(p VersionParser) VersionParser {
+	if p == nil {
+		return SemVerParser{}
+	}
+	return p
+}
+
+// parseVersion returns the version of the branch, branches without a version get the zero version
+func parseVersion(p VersionParser, branch string) ParsedVersion {
+	v, _ := versionParserOr(p).Parse(branch)
+	return v
+}
+
+// sortByVersion sorts the branches from the lowest to the highest version, branches without a version come first.
+// Branches with the same version keep their order.
+func sortByVersion(s []string, p VersionParser) {
+	versions := make(map[string]ParsedVersion, len(s))
+	for _, b := range s {
+		if v, ok := versionParserOr(p).Parse(b); ok {
+			versions[b] = v
+		}
+	}
+	sort.SliceStable(s, func(i, j int) bool {
+		a, aOk := versions[s[i]]
+		b, bOk := versions[s[j]]
+		if aOk != bOk {
+			return !aOk
+		}
+		return a.Compare(b) < 0
+	})
+}
+
+// Unversioned returns the branches without a version, e.g. release/next
+func Unversioned(branches []string, parser VersionParser) []string {
+	var unversioned []string
+	for _, b := range branches {
+		if _, ok := versionParserOr(parser).Parse(b); !ok {
+			unversioned = append(unversioned, b)
+		}
+	}
+	return unversioned
+}
+
+// ReleasedPrereleases returns the branches which are a pre-release of a version, whose release is one of the branches,
+// e.g. release/v2.0.0-rc.1 if release/v2.0.0 exists
+func ReleasedPrereleases(branches []string, parser VersionParser) []string {
+	var released []ParsedVersion
+	for _, b := range branches {
+		if v, ok := versionParserOr(parser).Parse(b); ok && !v.IsPrerelease() {
+			released = append(released, v)
+		}
+	}
+	var prereleases []string
+	for _, b := range branches {
+		v, ok := versionParserOr(parser).Parse(b)
+		if !ok || !v.IsPrerelease() {
+			continue
+		}
+		for _, r := range released {
+			if v.compareParts(r) == 0 {
+				prereleases = append(prereleases, b)
+				break
+			}
+		}
+	}
+	return prereleases
+}
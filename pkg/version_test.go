@@ -0,0 +1,120 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSemVerParser_Parse(t *testing.T) {
+	tests := []struct {
+		branch string
+		want   string
+		parts  []int
+	}{
+		{"refs/heads/release/v1.2.3", "v1.2.3", []int{1, 2, 3}},
+		{"refs/heads/release/1.4.2", "1.4.2", []int{1, 4, 2}},
+		{"refs/heads/release/v1.2.3.4", "v1.2.3.4", []int{1, 2, 3, 4}},
+		{"refs/heads/release/v1.02.010", "v1.02.010", []int{1, 2, 10}},
+		{"refs/heads/release-2.0", "2.0", []int{2, 0}},
+		{"refs/heads/release/v1.2.3.4.5", "", nil},
+		{"refs/heads/build1.2", "", nil},
+		{"refs/heads/master", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.branch, func(t *testing.T) {
+			v, ok := SemVerParser{}.Parse(tt.branch)
+			assert.Equal(t, tt.want != "", ok)
+			assert.Equal(t, tt.want, v.Original)
+			assert.Equal(t, tt.parts, v.Parts)
+		})
+	}
+}
+
+func TestParsedVersion_Compare(t *testing.T) {
+	v := func(parts ...int) ParsedVersion { return ParsedVersion{Parts: parts} }
+	assert.Equal(t, 0, v(1, 2).Compare(v(1, 2, 0)))
+	assert.Equal(t, -1, v(1, 2, 3).Compare(v(1, 2, 3, 1)))
+	assert.Equal(t, 1, v(1, 10).Compare(v(1, 9, 9)))
+}
+
+func Test_sortByVersion(t *testing.T) {
+	branches := []string{
+		"refs/heads/release/1.10.0", "refs/heads/release/v1.2.3.4", "refs/heads/release/v1.02.3", "refs/heads/release/1.9.0",
+	}
+	sortByVersion(branches, nil)
+	assert.Equal(t, []string{
+		"refs/heads/release/v1.02.3", "refs/heads/release/v1.2.3.4", "refs/heads/release/1.9.0", "refs/heads/release/1.10.0",
+	}, branches)
+}
+
+func TestFilterBranches_without_v_prefix(t *testing.T) {
+	branches := FilterBranches([]string{
+		"refs/heads/release/1.4.1", "refs/heads/release/1.4.2", "refs/heads/release/1.5.0",
+	}, DefaultRetentionPolicy())
+	assert.Equal(t, []string{"refs/heads/release/1.4.1"}, branches)
+}
+
+func TestCalVerParser_Parse(t *testing.T) {
+	tests := []struct {
+		branch string
+		want   string
+	}{
+		{"refs/heads/release/2024.03", "2024.03"},
+		{"refs/heads/release/2024.11.1", "2024.11.1"},
+		{"refs/heads/release/v2024.1", "v2024.1"},
+		{"refs/heads/release/2024.13", ""},
+		{"refs/heads/release/v1.2.3", ""},
+		{"refs/heads/release/2024.03.1.2", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.branch, func(t *testing.T) {
+			v, ok := CalVerParser{}.Parse(tt.branch)
+			assert.Equal(t, tt.want != "", ok)
+			assert.Equal(t, tt.want, v.Original)
+		})
+	}
+}
+
+func TestParseVersionScheme(t *testing.T) {
+	parser, err := ParseVersionScheme("calver")
+	assert.NoError(t, err)
+	assert.Equal(t, CalVerParser{}, parser)
+	parser, err = ParseVersionScheme("")
+	assert.NoError(t, err)
+	assert.Equal(t, SemVerParser{}, parser)
+	_, err = ParseVersionScheme("romver")
+	assert.Error(t, err)
+}
+
+func TestSemVerParser_Parse_prerelease(t *testing.T) {
+	v, ok := SemVerParser{}.Parse("refs/heads/release/v2.0.0-rc.1+build.5")
+	assert.True(t, ok)
+	assert.Equal(t, ParsedVersion{Original: "v2.0.0-rc.1+build.5", Parts: []int{2, 0, 0}, Prerelease: "rc.1", Build: "build.5"}, v)
+	assert.True(t, v.IsPrerelease())
+}
+
+func Test_sortByVersion_prerelease(t *testing.T) {
+	// Sorted like the semver precedence example 1.0.0-alpha < 1.0.0-alpha.1 < ... < 1.0.0
+	want := []string{"v1.0.0-alpha", "v1.0.0-alpha.1", "v1.0.0-alpha.beta", "v1.0.0-beta", "v1.0.0-beta.2",
+		"v1.0.0-beta.11", "v1.0.0-rc.1", "v1.0.0+build.1", "v1.0.1-0"}
+	branches := []string{"v1.0.1-0", "v1.0.0+build.1", "v1.0.0-rc.1", "v1.0.0-beta.11", "v1.0.0-beta.2", "v1.0.0-beta",
+		"v1.0.0-alpha.beta", "v1.0.0-alpha.1", "v1.0.0-alpha"}
+	sortByVersion(branches, nil)
+	assert.Equal(t, want, branches)
+}
+
+func TestReleasedPrereleases(t *testing.T) {
+	assert.Equal(t, []string{"refs/heads/release/v2.0.0-rc.1", "refs/heads/release/v2.0.0-beta"}, ReleasedPrereleases([]string{
+		"refs/heads/release/v2.0.0-rc.1", "refs/heads/release/v2.0.0-beta", "refs/heads/release/v2.0.0",
+		"refs/heads/release/v2.1.0-rc.1",
+	}, nil))
+}
+
+func TestUnversioned(t *testing.T) {
+	branches := []string{"refs/heads/release/v1.0.1", "refs/heads/release/next", "refs/heads/release/v1.0.0"}
+	assert.Equal(t, []string{"refs/heads/release/next"}, Unversioned(branches, nil))
+
+	sortByVersion(branches, nil)
+	assert.Equal(t, []string{"refs/heads/release/next", "refs/heads/release/v1.0.0", "refs/heads/release/v1.0.1"}, branches)
+}